@@ -0,0 +1,195 @@
+// Package auth authenticates requests by their credentials, like API keys,
+// basic auth, bearer tokens, client certificates and session cookies.
+//
+// Authenticators are configured per instance and chained in order by
+// middleware.Authenticate, so routers may use different credential stores:
+//
+//	r.Use(middleware.Authenticate(
+//		&auth.APIKey{Lookup: keys.Find},
+//		&auth.Bearer{JWTAuth: tokenAuth},
+//		&auth.Cookie{Name: "session", Lookup: sessions.Find},
+//	))
+package auth
+
+import (
+	"context"
+	"crypto/x509"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/lestrrat-go/jwx/v2/jwt"
+	"go.philip.id/phi/jwtauth"
+)
+
+var (
+	// ErrNoCredentials is returned by authenticators if the request doesn't
+	// carry credentials of their kind, the next authenticator is tried.
+	ErrNoCredentials = errors.New("no credentials")
+
+	// ErrInvalidCredentials is returned by authenticators if the request
+	// carries credentials of their kind which are unknown or invalid.
+	ErrInvalidCredentials = errors.New("invalid credentials")
+)
+
+// Authenticator returns the identity of the credentials of a request. It
+// returns ErrNoCredentials if the request doesn't carry credentials of its
+// kind, other errors reject the request.
+type Authenticator interface {
+	Authenticate(r *http.Request) (*Token, error)
+}
+
+// AuthenticatorFunc is an adapter to use functions as Authenticator.
+type AuthenticatorFunc func(r *http.Request) (*Token, error)
+
+func (fn AuthenticatorFunc) Authenticate(r *http.Request) (*Token, error) {
+	return fn(r)
+}
+
+// APIKey authenticates requests by an API key in a header.
+type APIKey struct {
+	// Header carrying the key, "X-API-Key" by default
+	Header string
+
+	// Lookup returns the token of the key, ErrInvalidCredentials if the key
+	// is unknown
+	Lookup func(ctx context.Context, key string) (*Token, error)
+}
+
+func (a *APIKey) Authenticate(r *http.Request) (*Token, error) {
+	header := a.Header
+	if header == "" {
+		header = "X-API-Key"
+	}
+
+	key := r.Header.Get(header)
+	if key == "" {
+		return nil, ErrNoCredentials
+	}
+
+	return lookup("apikey", func() (*Token, error) {
+		return a.Lookup(r.Context(), key)
+	})
+}
+
+// Basic authenticates requests by the username and password of basic auth.
+type Basic struct {
+	// Lookup returns the token of the user, ErrInvalidCredentials if the
+	// user is unknown or the password doesn't match
+	Lookup func(ctx context.Context, username, password string) (*Token, error)
+}
+
+func (a *Basic) Authenticate(r *http.Request) (*Token, error) {
+	username, password, ok := r.BasicAuth()
+	if !ok {
+		return nil, ErrNoCredentials
+	}
+
+	return lookup("basic", func() (*Token, error) {
+		return a.Lookup(r.Context(), username, password)
+	})
+}
+
+// Bearer authenticates requests by a jwt in the "Authorization: Bearer"
+// header, verified by jwtauth.VerifyRequest.
+type Bearer struct {
+	JWTAuth *jwtauth.JWTAuth
+
+	// DecodeClaims decodes the claims into Token.TypedClaims, see
+	// DecodeClaims. Tokens whose claims fail to decode are invalid.
+	DecodeClaims func(token jwt.Token) (interface{}, error)
+}
+
+func (a *Bearer) Authenticate(r *http.Request) (*Token, error) {
+	if jwtauth.TokenFromHeader(r) == "" {
+		return nil, ErrNoCredentials
+	}
+
+	token, err := jwtauth.VerifyRequest(a.JWTAuth, r, jwtauth.TokenFromHeader)
+	if err != nil {
+		return nil, ErrInvalidCredentials
+	}
+
+	t := TokenFromJWT(token)
+	t.Method = "bearer"
+
+	if a.DecodeClaims != nil {
+		if t.TypedClaims, err = a.DecodeClaims(token); err != nil {
+			return nil, ErrInvalidCredentials
+		}
+	}
+
+	return t, nil
+}
+
+// ClientCert authenticates requests by a TLS client certificate verified by
+// the server, see tls.Config.ClientAuth.
+type ClientCert struct {
+	// Lookup returns the token of the certificate. If nil, the subject of
+	// the token is the common name of the certificate.
+	Lookup func(ctx context.Context, cert *x509.Certificate) (*Token, error)
+}
+
+func (a *ClientCert) Authenticate(r *http.Request) (*Token, error) {
+	if r.TLS == nil || len(r.TLS.VerifiedChains) == 0 || len(r.TLS.VerifiedChains[0]) == 0 {
+		return nil, ErrNoCredentials
+	}
+	cert := r.TLS.VerifiedChains[0][0]
+
+	if a.Lookup == nil {
+		return &Token{
+			ID:        cert.SerialNumber.String(),
+			Subject:   cert.Subject.CommonName,
+			ExpiresAt: timeOf(cert.NotAfter),
+			NotBefore: timeOf(cert.NotBefore),
+			Method:    "mtls",
+		}, nil
+	}
+
+	return lookup("mtls", func() (*Token, error) {
+		return a.Lookup(r.Context(), cert)
+	})
+}
+
+// Cookie authenticates requests by a session cookie.
+type Cookie struct {
+	// Name of the cookie, "session" by default
+	Name string
+
+	// Lookup returns the token of the session, ErrInvalidCredentials if the
+	// session is unknown or expired
+	Lookup func(ctx context.Context, session string) (*Token, error)
+}
+
+func (a *Cookie) Authenticate(r *http.Request) (*Token, error) {
+	name := a.Name
+	if name == "" {
+		name = "session"
+	}
+
+	cookie, err := r.Cookie(name)
+	if err != nil || strings.TrimSpace(cookie.Value) == "" {
+		return nil, ErrNoCredentials
+	}
+
+	return lookup("cookie", func() (*Token, error) {
+		return a.Lookup(r.Context(), cookie.Value)
+	})
+}
+
+// lookup calls the lookup function of an authenticator and sets the method
+// of the token
+func lookup(method string, fn func() (*Token, error)) (*Token, error) {
+	token, err := fn()
+	if err != nil {
+		return nil, err
+	}
+	if token == nil {
+		return nil, ErrInvalidCredentials
+	}
+
+	if token.Method == "" {
+		token.Method = method
+	}
+	return token, nil
+}
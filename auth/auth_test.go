@@ -0,0 +1,111 @@
+package auth
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"go.philip.id/phi/jwtauth"
+)
+
+type tenantClaims struct {
+	TenantID string `json:"tenant_id"`
+}
+
+func TestAuthenticators(t *testing.T) {
+	tokenAuth := jwtauth.New("HS256", []byte("secretpass"), nil)
+	_, jwt, _ := tokenAuth.Encode(map[string]interface{}{"sub": "philip", "scope": "orders:read", "tenant_id": "acme"})
+
+	keys := map[string]string{"k1": "service"}
+	apiKey := &APIKey{Lookup: func(ctx context.Context, key string) (*Token, error) {
+		if sub, ok := keys[key]; ok {
+			return &Token{Subject: sub}, nil
+		}
+		return nil, ErrInvalidCredentials
+	}}
+
+	basic := &Basic{Lookup: func(ctx context.Context, username, password string) (*Token, error) {
+		if username == "philip" && password == "secret" {
+			return &Token{Subject: username}, nil
+		}
+		return nil, ErrInvalidCredentials
+	}}
+
+	cookie := &Cookie{Name: "sid", Lookup: func(ctx context.Context, session string) (*Token, error) {
+		if session == "s1" {
+			return &Token{Subject: "philip"}, nil
+		}
+		return nil, nil
+	}}
+
+	bearer := &Bearer{JWTAuth: tokenAuth, DecodeClaims: DecodeClaims[tenantClaims]()}
+
+	tests := []struct {
+		name    string
+		a       Authenticator
+		prepare func(r *http.Request)
+		subject string
+		method  string
+		err     error
+	}{
+		{"api key", apiKey, func(r *http.Request) { r.Header.Set("X-API-Key", "k1") }, "service", "apikey", nil},
+		{"unknown api key", apiKey, func(r *http.Request) { r.Header.Set("X-API-Key", "k2") }, "", "", ErrInvalidCredentials},
+		{"no api key", apiKey, func(r *http.Request) {}, "", "", ErrNoCredentials},
+		{"basic", basic, func(r *http.Request) { r.SetBasicAuth("philip", "secret") }, "philip", "basic", nil},
+		{"wrong password", basic, func(r *http.Request) { r.SetBasicAuth("philip", "guess") }, "", "", ErrInvalidCredentials},
+		{"bearer", bearer, func(r *http.Request) { r.Header.Set("Authorization", "Bearer "+jwt) }, "philip", "bearer", nil},
+		{"invalid bearer", bearer, func(r *http.Request) { r.Header.Set("Authorization", "Bearer x.y.z") }, "", "", ErrInvalidCredentials},
+		{"cookie", cookie, func(r *http.Request) { r.AddCookie(&http.Cookie{Name: "sid", Value: "s1"}) }, "philip", "cookie", nil},
+		{"unknown session", cookie, func(r *http.Request) { r.AddCookie(&http.Cookie{Name: "sid", Value: "s2"}) }, "", "", ErrInvalidCredentials},
+		{"client cert", &ClientCert{}, func(r *http.Request) {
+			r.TLS = &tls.ConnectionState{VerifiedChains: [][]*x509.Certificate{{{
+				SerialNumber: big.NewInt(7),
+				Subject:      pkix.Name{CommonName: "billing-service"},
+			}}}}
+		}, "billing-service", "mtls", nil},
+		{"no client cert", &ClientCert{}, func(r *http.Request) { r.TLS = &tls.ConnectionState{} }, "", "", ErrNoCredentials},
+	}
+
+	for _, tt := range tests {
+		r := httptest.NewRequest("GET", "/", nil)
+		tt.prepare(r)
+
+		token, err := tt.a.Authenticate(r)
+		if err != tt.err {
+			t.Errorf("%s: expected error %v, got %v", tt.name, tt.err, err)
+			continue
+		}
+		if err == nil && (token.Subject != tt.subject || token.Method != tt.method) {
+			t.Errorf("%s: unexpected token %+v", tt.name, token)
+		}
+	}
+
+	r := httptest.NewRequest("GET", "/", nil)
+	r.Header.Set("Authorization", "Bearer "+jwt)
+	token, _ := bearer.Authenticate(r)
+	if claims, ok := token.TypedClaims.(*tenantClaims); !ok || claims.TenantID != "acme" || len(token.Scopes) != 1 {
+		t.Errorf("unexpected bearer token %+v", token)
+	}
+}
+
+func TestTokenJSON(t *testing.T) {
+	tokenAuth := jwtauth.New("HS256", []byte("secretpass"), nil)
+
+	token, _, _ := tokenAuth.Encode(map[string]interface{}{"sub": "philip"})
+	data, _ := json.Marshal(TokenFromJWT(token))
+	if string(data) != `{"id":"","subject":"philip"}` {
+		t.Errorf("unexpected json %s", data)
+	}
+
+	token, _, _ = tokenAuth.Encode(map[string]interface{}{"sub": "philip", "exp": 1700000000})
+	data, _ = json.Marshal(TokenFromJWT(token))
+	if string(data) != `{"id":"","subject":"philip","expiresAt":"2023-11-14T22:13:20Z"}` {
+		t.Errorf("unexpected json %s", data)
+	}
+}
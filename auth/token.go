@@ -0,0 +1,112 @@
+package auth
+
+import (
+	"encoding/json"
+	"strings"
+	"time"
+
+	"github.com/lestrrat-go/jwx/v2/jwt"
+)
+
+// Token is the identity of an authenticated request, stored by
+// middleware.Authenticate under middleware.TOKEN_CONTEXT.
+type Token struct {
+	ID      string `json:"id"`
+	Subject string `json:"subject"`
+
+	// Registered claims, the times are nil if their claim is not set
+	Issuer    string     `json:"issuer,omitempty"`
+	Audience  []string   `json:"audience,omitempty"`
+	ExpiresAt *time.Time `json:"expiresAt,omitempty"`
+	IssuedAt  *time.Time `json:"issuedAt,omitempty"`
+	NotBefore *time.Time `json:"notBefore,omitempty"`
+
+	// Scopes of the "scope" (space separated), "scp" or "scopes" claim
+	Scopes []string `json:"scopes,omitempty"`
+
+	// Roles of the "roles" or "role" claim
+	Roles []string `json:"roles,omitempty"`
+
+	// Claims are the private claims of the token, the registered claims are
+	// mapped to the fields above
+	Claims map[string]interface{} `json:"claims,omitempty"`
+
+	// Method is the kind of credentials the request was authenticated with,
+	// f.e. "bearer" or "apikey"
+	Method string `json:"method,omitempty"`
+
+	// TypedClaims are the claims decoded into a type of your own, see
+	// DecodeClaims and middleware.GetClaims
+	TypedClaims interface{} `json:"-"`
+}
+
+// TokenFromJWT maps the claims of a verified jwt to a Token.
+func TokenFromJWT(token jwt.Token) *Token {
+	t := &Token{
+		ID:        token.JwtID(),
+		Subject:   token.Subject(),
+		Issuer:    token.Issuer(),
+		Audience:  token.Audience(),
+		ExpiresAt: timeOf(token.Expiration()),
+		IssuedAt:  timeOf(token.IssuedAt()),
+		NotBefore: timeOf(token.NotBefore()),
+		Claims:    token.PrivateClaims(),
+	}
+
+	if scope, ok := t.Claims["scope"].(string); ok {
+		t.Scopes = strings.Fields(scope)
+	} else {
+		t.Scopes = claimStrings(t.Claims, "scp", "scopes")
+	}
+	t.Roles = claimStrings(t.Claims, "roles", "role")
+
+	return t
+}
+
+// DecodeClaims returns a function decoding the claims of tokens into T by
+// its json tags, see Bearer.DecodeClaims.
+func DecodeClaims[T any]() func(token jwt.Token) (interface{}, error) {
+	return func(token jwt.Token) (interface{}, error) {
+		data, err := json.Marshal(token)
+		if err != nil {
+			return nil, err
+		}
+
+		claims := new(T)
+		if err := json.Unmarshal(data, claims); err != nil {
+			return nil, err
+		}
+		return claims, nil
+	}
+}
+
+// timeOf returns a pointer to t, nil if t is zero
+func timeOf(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+	return &t
+}
+
+// claimStrings returns the values of the first of the claims set, as list
+// of strings or space separated string
+func claimStrings(claims map[string]interface{}, names ...string) []string {
+	for _, name := range names {
+		switch v := claims[name].(type) {
+		case string:
+			return strings.Fields(v)
+		case []string:
+			return v
+		case []interface{}:
+			values := make([]string, 0, len(v))
+			for _, value := range v {
+				if s, ok := value.(string); ok {
+					values = append(values, s)
+				}
+			}
+			return values
+		}
+	}
+
+	return nil
+}
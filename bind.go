@@ -0,0 +1,297 @@
+package phi
+
+import (
+	"encoding"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	timeType         = reflect.TypeOf(time.Time{})
+	durationType     = reflect.TypeOf(time.Duration(0))
+	textUnmarshalerT = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+)
+
+// BindQuery fills a struct from the query params of the request, fields are
+// mapped by their `query` tag and validated by their `validate` tag like
+// request bodies:
+//
+//	type ListParams struct {
+//		Page  int           `query:"page" default:"1" validate:"min=1"`
+//		Tags  []string      `query:"tag"` // ?tag=a&tag=b
+//		Since *time.Time    `query:"since"`
+//		Wait  time.Duration `query:"wait" default:"5s"`
+//	}
+//
+//	params, err := phi.BindQuery[ListParams](r)
+//
+// Supported are strings, ints, uints, floats, bools, time.Time (RFC 3339 or
+// 2006-01-02), time.Duration, encoding.TextUnmarshaler, slices and pointers
+// of them. Params missing from the request get the `default` of their field
+// or stay zero, pointers tell missing params (nil) from empty ones. Empty
+// params of non-string fields count as missing. Missing and invalid params
+// are returned as QueryParameterError, its Details tell them apart by the
+// violated rule of each field.
+func BindQuery[T any](r *Request) (*T, *Error) {
+	query := r.URL.Query()
+
+	return bindValues[T]("query", func(key string) []string { return query[key] }, QueryParameterError)
+}
+
+// BindHeader fills a struct from the headers of the request, fields are
+// mapped by their `header` tag, see BindQuery:
+//
+//	type Tenant struct {
+//		ID      string `header:"X-Tenant" validate:"required"`
+//		Retries int    `header:"X-Retries" default:"3"`
+//	}
+//
+// Failing headers are returned as HeaderParameterError detailing each field.
+func BindHeader[T any](r *Request) (*T, *Error) {
+	header := r.Header
+
+	return bindValues[T]("header", func(key string) []string {
+		return header.Values(http.CanonicalHeaderKey(key))
+	}, HeaderParameterError)
+}
+
+// bindValues fills a struct of type T from the values of `lookup` by the
+// struct tag `tagName` and validates it
+func bindValues[T any](tagName string, lookup func(key string) []string, newError func(string) *Error) (*T, *Error) {
+	var data T
+
+	v := reflect.ValueOf(&data).Elem()
+	if v.Kind() != reflect.Struct {
+		return nil, &Error{
+			Error:      "bindingFailed",
+			Message:    fmt.Sprintf("cannot bind %s to %T", tagName, data),
+			StatusCode: http.StatusInternalServerError,
+		}
+	}
+
+	errs := []fieldError{}
+	if err := bindStruct(tagName, v, lookup, &errs); err != nil {
+		return nil, &Error{
+			Error:      "bindingFailed",
+			Message:    err.Error(),
+			StatusCode: http.StatusInternalServerError,
+		}
+	}
+
+	if len(errs) == 0 {
+		return &data, nil
+	}
+
+	return nil, validationError(errs, newError, newError)
+}
+
+// bindStruct sets the tagged fields of the struct value, embedded structs
+// are bound as well
+func bindStruct(tagName string, v reflect.Value, lookup func(key string) []string, errs *[]fieldError) error {
+	for i := 0; i < v.NumField(); i++ {
+		field := v.Type().Field(i)
+		name := field.Tag.Get(tagName)
+
+		if field.Anonymous && name == "" && field.Type.Kind() == reflect.Struct {
+			if err := bindStruct(tagName, v.Field(i), lookup, errs); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if !field.IsExported() || name == "" || name == "-" {
+			continue
+		}
+
+		elemType := bindElemType(field.Type)
+		if elemType != fileHeaderType && !bindable(elemType) {
+			return fmt.Errorf("field %s: unsupported type %s", name, field.Type)
+		}
+
+		rules, err := parseRules(field.Tag.Get("validate"))
+		if err != nil {
+			return fmt.Errorf("field %s: %w", name, err)
+		}
+
+		values := lookup(name)
+		if elemType.Kind() != reflect.String {
+			values = nonEmpty(values)
+		}
+
+		switch {
+		case elemType == fileHeaderType:
+			// files are set by BindMultipart, they are only validated
+
+		case len(values) == 0:
+			if def, ok := field.Tag.Lookup("default"); ok {
+				values = []string{def}
+				if field.Type.Kind() == reflect.Slice {
+					values = strings.Split(def, ",")
+				}
+
+				if rule, _, err := bindValue(v.Field(i), values); err != nil || rule != "" {
+					return fmt.Errorf("field %s: invalid default '%s'", name, def)
+				}
+			}
+
+		default:
+			rule, rejected, err := bindValue(v.Field(i), values)
+			if err != nil {
+				return fmt.Errorf("field %s: %w", name, err)
+			}
+			if rule != "" {
+				e := fieldError{path: name, rule: rule}
+				if !hasRule(rules, "secret") {
+					e.value = rejected
+				}
+				*errs = append(*errs, e)
+				continue
+			}
+		}
+
+		if err := handleField(name, errs, v.Field(i), rules, v); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// bindValue sets v to the values, values failing to parse are returned
+// along the name of the expected type as rule. Unsupported types are
+// reported as error.
+func bindValue(v reflect.Value, values []string) (rule, rejected string, err error) {
+	if v.Kind() == reflect.Ptr {
+		elem := reflect.New(v.Type().Elem())
+		if rule, rejected, err = bindValue(elem.Elem(), values); err != nil || rule != "" {
+			return rule, rejected, err
+		}
+		v.Set(elem)
+		return "", "", nil
+	}
+
+	if v.Kind() == reflect.Slice && !v.Type().Implements(textUnmarshalerT) && !reflect.PtrTo(v.Type()).Implements(textUnmarshalerT) {
+		s := reflect.MakeSlice(v.Type(), len(values), len(values))
+		for i, value := range values {
+			if rule, rejected, err = bindValue(s.Index(i), []string{value}); err != nil || rule != "" {
+				return rule, rejected, err
+			}
+		}
+		v.Set(s)
+		return "", "", nil
+	}
+
+	rule, err = parseValue(v, values[0])
+	return rule, values[0], err
+}
+
+// parseValue sets the scalar v to the parsed value, it returns the name of
+// the expected type if the value fails to parse
+func parseValue(v reflect.Value, value string) (string, error) {
+	switch v.Type() {
+	case timeType:
+		t, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			if t, err = time.Parse("2006-01-02", value); err != nil {
+				return "time", nil
+			}
+		}
+		v.Set(reflect.ValueOf(t))
+		return "", nil
+
+	case durationType:
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			return "duration", nil
+		}
+		v.SetInt(int64(d))
+		return "", nil
+	}
+
+	if u, ok := v.Addr().Interface().(encoding.TextUnmarshaler); ok {
+		if err := u.UnmarshalText([]byte(value)); err != nil {
+			return "value", nil
+		}
+		return "", nil
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(value)
+
+	case reflect.Bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return "bool", nil
+		}
+		v.SetBool(b)
+
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(value, 10, v.Type().Bits())
+		if err != nil {
+			return "int", nil
+		}
+		v.SetInt(n)
+
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(value, 10, v.Type().Bits())
+		if err != nil {
+			return "uint", nil
+		}
+		v.SetUint(n)
+
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(value, v.Type().Bits())
+		if err != nil {
+			return "number", nil
+		}
+		v.SetFloat(f)
+
+	default:
+		return "", fmt.Errorf("unsupported type %s", v.Type())
+	}
+
+	return "", nil
+}
+
+// bindElemType returns the type values of fields of type t are parsed into
+func bindElemType(t reflect.Type) reflect.Type {
+	for t.Kind() == reflect.Ptr || (t.Kind() == reflect.Slice && !t.Implements(textUnmarshalerT) && !reflect.PtrTo(t).Implements(textUnmarshalerT)) {
+		t = t.Elem()
+	}
+
+	return t
+}
+
+// bindable reports whether values can be parsed into the type
+func bindable(t reflect.Type) bool {
+	if t == timeType || t == durationType || reflect.PtrTo(t).Implements(textUnmarshalerT) {
+		return true
+	}
+
+	switch t.Kind() {
+	case reflect.String, reflect.Bool,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return true
+	}
+
+	return false
+}
+
+// nonEmpty returns the values without empty strings
+func nonEmpty(values []string) []string {
+	out := values[:0:0]
+	for _, value := range values {
+		if value != "" {
+			out = append(out, value)
+		}
+	}
+
+	return out
+}
@@ -0,0 +1,133 @@
+package phi
+
+import (
+	"net"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+)
+
+type listParams struct {
+	Page    int           `query:"page" default:"1" validate:"min=1"`
+	Limit   *uint8        `query:"limit"`
+	Search  *string       `query:"q"`
+	Sort    string        `query:"sort" default:"name" validate:"oneof=name date"`
+	Tags    []string      `query:"tag" validate:"max=3"`
+	IDs     []int64       `query:"id"`
+	Deleted bool          `query:"deleted"`
+	Since   time.Time     `query:"since"`
+	Wait    time.Duration `query:"wait" default:"5s"`
+	IP      net.IP        `query:"ip"`
+	Ignored string
+}
+
+func TestBindQuery(t *testing.T) {
+	r := &Request{httptest.NewRequest("GET", "/?page=3&q=&tag=a&tag=b&id=7&id=9&deleted=true&since=2024-05-12&limit=&ip=10.0.0.1", nil)}
+
+	params, err := BindQuery[listParams](r)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	since, _ := time.Parse("2006-01-02", "2024-05-12")
+	expected := listParams{
+		Page:    3,
+		Search:  new(string),
+		Sort:    "name",
+		Tags:    []string{"a", "b"},
+		IDs:     []int64{7, 9},
+		Deleted: true,
+		Since:   since,
+		Wait:    5 * time.Second,
+		IP:      net.ParseIP("10.0.0.1"),
+	}
+	if !reflect.DeepEqual(*params, expected) {
+		t.Errorf("expected %+v, got %+v", expected, *params)
+	}
+
+	r = &Request{httptest.NewRequest("GET", "/?page=0&limit=300&sort=size&id=1&id=x&since=yesterday", nil)}
+	_, err = BindQuery[listParams](r)
+	if err == nil {
+		t.Fatal("expected error")
+	}
+	if err.Error != "missingQueryParameters" || err.StatusCode != 400 {
+		t.Errorf("expected query parameter error, got %s %d", err.Error, err.StatusCode)
+	}
+
+	msg := "invalid 'page' (must be at least 1), 'limit' (must be a valid uint), 'sort' (must be one of [name date]), 'id' (must be a valid int), 'since' (must be a valid time)"
+	if err.Message != msg {
+		t.Errorf("expected message %q, got %q", msg, err.Message)
+	}
+
+	rules := []string{}
+	for _, d := range err.Details {
+		rules = append(rules, d.Path+":"+d.Rule)
+	}
+	if !reflect.DeepEqual(rules, []string{"page:min", "limit:uint", "sort:oneof", "id:int", "since:time"}) {
+		t.Errorf("unexpected details %v", err.Details)
+	}
+	if err.Details[3].Value != "x" {
+		t.Errorf("expected rejected value x, got %v", err.Details[3].Value)
+	}
+
+	r = &Request{httptest.NewRequest("GET", "/", nil)}
+	_, err = BindQuery[struct {
+		ID int `query:"id" validate:"required"`
+	}](r)
+	if err == nil || err.Error != "missingQueryParameters" || err.Message != "missing 'id'" {
+		t.Errorf("expected missing query parameter, got %+v", err)
+	}
+
+	r = &Request{httptest.NewRequest("GET", "/?pin=x", nil)}
+	_, err = BindQuery[struct {
+		PIN int `query:"pin" validate:"secret"`
+	}](r)
+	if err == nil || len(err.Details) != 1 || err.Details[0].Value != nil {
+		t.Errorf("expected secret value to be omitted, got %+v", err)
+	}
+
+	// programming errors
+	r = &Request{httptest.NewRequest("GET", "/?page=", nil)}
+	if _, err := BindQuery[struct {
+		Page int `query:"page" default:"first"`
+	}](r); err == nil || err.StatusCode != 500 {
+		t.Errorf("expected invalid default to fail, got %v", err)
+	}
+	if _, err := BindQuery[struct {
+		Page map[string]int `query:"page"`
+	}](r); err == nil || err.StatusCode != 500 {
+		t.Errorf("expected unsupported type to fail, got %v", err)
+	}
+	if _, err := BindQuery[[]int](r); err == nil || err.StatusCode != 500 {
+		t.Errorf("expected non-struct to fail, got %v", err)
+	}
+}
+
+func TestBindHeader(t *testing.T) {
+	type pagination struct {
+		Cursor string `header:"x-cursor"`
+	}
+	type tenant struct {
+		pagination
+		ID      string `header:"X-Tenant" validate:"required"`
+		Retries int    `header:"X-Retries" default:"3"`
+	}
+
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set("X-Tenant", "acme")
+	req.Header.Set("X-Cursor", "abc")
+
+	h, err := BindHeader[tenant](&Request{req})
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if h.ID != "acme" || h.Retries != 3 || h.Cursor != "abc" {
+		t.Errorf("unexpected headers %+v", h)
+	}
+
+	_, err = BindHeader[tenant](&Request{httptest.NewRequest("GET", "/", nil)})
+	if err == nil || err.Error != "invalidHeaders" || err.Message != "missing 'X-Tenant'" {
+		t.Errorf("expected missing header, got %+v", err)
+	}
+}
@@ -10,13 +10,15 @@ func Chain(middlewares ...func(http.Handler) http.Handler) Middlewares {
 // Handler builds and returns a http.Handler from the chain of middlewares,
 // with `h http.Handler` as the final handler.
 func (mws Middlewares) Handler(h http.Handler) http.Handler {
-	return &ChainHandler{h, chain(mws, h), mws}
+	c := &ChainHandler{Endpoint: h, Middlewares: mws}
+	c.chain, c.requirements = chain(mws, h)
+	return c
 }
 
 // HandlerFunc builds and returns a http.Handler from the chain of middlewares,
 // with `h http.Handler` as the final handler.
 func (mws Middlewares) HandlerFunc(h http.HandlerFunc) http.Handler {
-	return &ChainHandler{h, chain(mws, h), mws}
+	return mws.Handler(h)
 }
 
 // ChainHandler is a http.Handler with support for handler composition and
@@ -25,6 +27,9 @@ type ChainHandler struct {
 	Endpoint    http.Handler
 	chain       http.Handler
 	Middlewares Middlewares
+
+	// requirements of the guards among the middlewares, see HandlerRequirements
+	requirements Requirements
 }
 
 func (c *ChainHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
@@ -32,18 +37,24 @@ func (c *ChainHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 }
 
 // chain builds a http.Handler composed of an inline middleware stack and endpoint
-// handler in the order they are passed.
-func chain(middlewares []func(http.Handler) http.Handler, endpoint http.Handler) http.Handler {
+// handler in the order they are passed. It also returns the requirements of
+// the middlewares wrapping the endpoint with a GuardHandler.
+func chain(middlewares []func(http.Handler) http.Handler, endpoint http.Handler) (http.Handler, Requirements) {
+	var rq Requirements
+
 	// Return ahead of time if there aren't any middlewares for the chain
 	if len(middlewares) == 0 {
-		return endpoint
+		return endpoint, rq
 	}
 
 	// Wrap the end handler with the middleware chain
-	h := middlewares[len(middlewares)-1](endpoint)
-	for i := len(middlewares) - 2; i >= 0; i-- {
+	h := endpoint
+	for i := len(middlewares) - 1; i >= 0; i-- {
 		h = middlewares[i](h)
+		if gh, ok := h.(GuardHandler); ok {
+			rq.merge(gh.Requirements())
+		}
 	}
 
-	return h
+	return h, rq
 }
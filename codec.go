@@ -0,0 +1,201 @@
+package phi
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"io"
+	"mime"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Codec encodes and decodes values of a media type, it is used by
+// Response.Render to write and by Bind to read bodies.
+//
+// Codecs which can only encode some values, like protobuf messages, may
+// additionally implement
+//
+//	Supports(v interface{}) bool
+//
+// to be skipped during content negotiation for other values.
+type Codec interface {
+	Encode(w io.Writer, v interface{}) error
+	Decode(r io.Reader, v interface{}) error
+}
+
+// JSONCodec and XMLCodec are registered by default for application/json and
+// application/xml. The render subpackage registers MessagePack, CBOR, YAML
+// and protobuf codecs.
+var (
+	JSONCodec Codec = jsonCodec{}
+	XMLCodec  Codec = xmlCodec{}
+)
+
+// codecs registered by media type, the order of registration decides which
+// codec answers requests accepting any media type
+var codecs = struct {
+	sync.RWMutex
+	types  []string
+	byType map[string]Codec
+}{
+	types: []string{"application/json", "application/xml", "text/xml"},
+	byType: map[string]Codec{
+		"application/json": JSONCodec,
+		"application/xml":  XMLCodec,
+		"text/xml":         XMLCodec,
+	},
+}
+
+// RegisterCodec registers the codec for the media type, replacing the codec
+// registered before, f.e.:
+//
+//	phi.RegisterCodec("application/vnd.api+json", phi.JSONCodec)
+func RegisterCodec(mediaType string, c Codec) {
+	mediaType = strings.ToLower(mediaType)
+
+	codecs.Lock()
+	defer codecs.Unlock()
+
+	if _, ok := codecs.byType[mediaType]; !ok {
+		codecs.types = append(codecs.types, mediaType)
+	}
+	codecs.byType[mediaType] = c
+}
+
+// lookupCodec returns the codec registered for the media type of a
+// Content-Type header, requests without content type are treated as json
+func lookupCodec(contentType string) (string, Codec) {
+	mediaType := "application/json"
+	if contentType != "" {
+		mt, _, err := mime.ParseMediaType(contentType)
+		if err != nil {
+			return contentType, nil
+		}
+		mediaType = mt
+	}
+
+	codecs.RLock()
+	defer codecs.RUnlock()
+
+	return mediaType, codecs.byType[mediaType]
+}
+
+// acceptRange is a single media range of an Accept header
+type acceptRange struct {
+	mediaType string
+	q         float64
+}
+
+// parseAccept returns the media ranges of the Accept header ordered by their
+// quality, more specific ranges first. An empty header accepts everything.
+func parseAccept(accept string) []acceptRange {
+	if strings.TrimSpace(accept) == "" {
+		return []acceptRange{{mediaType: "*/*", q: 1}}
+	}
+
+	ranges := []acceptRange{}
+	for _, part := range strings.Split(accept, ",") {
+		params := strings.Split(part, ";")
+
+		r := acceptRange{mediaType: strings.ToLower(strings.TrimSpace(params[0])), q: 1}
+		if r.mediaType == "" {
+			continue
+		}
+
+		for _, p := range params[1:] {
+			k, v, _ := strings.Cut(strings.TrimSpace(p), "=")
+			if strings.EqualFold(k, "q") {
+				if q, err := strconv.ParseFloat(v, 64); err == nil {
+					r.q = q
+				}
+			}
+		}
+
+		ranges = append(ranges, r)
+	}
+
+	sort.SliceStable(ranges, func(i, j int) bool {
+		if ranges[i].q != ranges[j].q {
+			return ranges[i].q > ranges[j].q
+		}
+		return strings.Count(ranges[i].mediaType, "*") < strings.Count(ranges[j].mediaType, "*")
+	})
+
+	return ranges
+}
+
+// negotiate picks the media type and codec to encode v with for the Accept
+// header, the codec is nil if none is acceptable
+func negotiate(accept string, v interface{}) (string, Codec) {
+	ranges := parseAccept(accept)
+
+	// media types explicitly refused with q=0
+	refused := map[string]bool{}
+	for _, r := range ranges {
+		if r.q <= 0 {
+			refused[r.mediaType] = true
+		}
+	}
+
+	codecs.RLock()
+	defer codecs.RUnlock()
+
+	for _, r := range ranges {
+		if r.q <= 0 {
+			continue
+		}
+
+		for _, mediaType := range codecs.types {
+			if refused[mediaType] || !matchMediaRange(r.mediaType, mediaType) {
+				continue
+			}
+
+			c := codecs.byType[mediaType]
+			if s, ok := c.(interface{ Supports(v interface{}) bool }); ok && !s.Supports(v) {
+				continue
+			}
+
+			return mediaType, c
+		}
+	}
+
+	return "", nil
+}
+
+// matchMediaRange reports whether the media type is in the range like
+// */*, application/* or application/json
+func matchMediaRange(mediaRange, mediaType string) bool {
+	if mediaRange == "*/*" || mediaRange == mediaType {
+		return true
+	}
+
+	return strings.HasSuffix(mediaRange, "/*") && strings.HasPrefix(mediaType, strings.TrimSuffix(mediaRange, "*"))
+}
+
+type jsonCodec struct{}
+
+func (jsonCodec) Encode(w io.Writer, v interface{}) error {
+	parsed, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+
+	_, err = w.Write(parsed)
+	return err
+}
+
+func (jsonCodec) Decode(r io.Reader, v interface{}) error {
+	return json.NewDecoder(r).Decode(v)
+}
+
+type xmlCodec struct{}
+
+func (xmlCodec) Encode(w io.Writer, v interface{}) error {
+	return xml.NewEncoder(w).Encode(v)
+}
+
+func (xmlCodec) Decode(r io.Reader, v interface{}) error {
+	return xml.NewDecoder(r).Decode(v)
+}
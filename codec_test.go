@@ -0,0 +1,114 @@
+package phi
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+type renderBody struct {
+	Name string `json:"name" xml:"name" validate:"required"`
+}
+
+func TestRender(t *testing.T) {
+	r := NewRouter()
+	r.GET("/", func(w *Response, r *Request) *Error {
+		return w.Render(renderBody{Name: "phi"})
+	})
+
+	tests := []struct {
+		accept      string
+		status      int
+		contentType string
+		body        string
+	}{
+		{"", 200, "application/json", `{"name":"phi"}`},
+		{"*/*", 200, "application/json", `{"name":"phi"}`},
+		{"text/html, application/xml;q=0.9", 200, "application/xml", `<renderBody><name>phi</name></renderBody>`},
+		{"application/json;q=0.5, text/xml", 200, "text/xml", `<renderBody><name>phi</name></renderBody>`},
+		{"application/*;q=0.8, application/json;q=0", 200, "application/xml", `<renderBody><name>phi</name></renderBody>`},
+		{"text/html", 406, "application/json", `{"error":"notAcceptable","message":"no representation available for 'text/html'"}`},
+	}
+
+	for _, tt := range tests {
+		req := httptest.NewRequest("GET", "/", nil)
+		if tt.accept != "" {
+			req.Header.Set("Accept", tt.accept)
+		}
+
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+
+		if w.Code != tt.status || w.Header().Get("Content-Type") != tt.contentType || w.Body.String() != tt.body {
+			t.Errorf("%q: unexpected response %d %s %s", tt.accept, w.Code, w.Header().Get("Content-Type"), w.Body.String())
+		}
+	}
+}
+
+func TestBind(t *testing.T) {
+	r := NewRouter()
+	r.POST("/", func(w *Response, r *Request) *Error {
+		body, err := Bind[renderBody](r)
+		if err != nil {
+			return err
+		}
+
+		return w.Response([]byte(body.Name), "text/plain")
+	})
+
+	tests := []struct {
+		contentType string
+		body        string
+		status      int
+		resp        string
+	}{
+		{"", `{"name":"json"}`, 200, "json"},
+		{"application/json; charset=utf-8", `{"name":"json"}`, 200, "json"},
+		{"application/xml", `<renderBody><name>xml</name></renderBody>`, 200, "xml"},
+		{"application/xml", `<renderBody></renderBody>`, 400, `{"error":"missingBodyParameters","errors":[{"path":"name","rule":"required","message":"is required"}],"message":"missing 'name'"}`},
+		{"text/plain", `name`, 415, `{"error":"unsupportedMediaType","message":"unsupported content type 'text/plain'"}`},
+	}
+
+	for _, tt := range tests {
+		req := httptest.NewRequest("POST", "/", strings.NewReader(tt.body))
+		if tt.contentType != "" {
+			req.Header.Set("Content-Type", tt.contentType)
+		}
+
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+
+		if w.Code != tt.status || w.Body.String() != tt.resp {
+			t.Errorf("%q: unexpected response %d %s", tt.contentType, w.Code, w.Body.String())
+		}
+	}
+}
+
+func TestRegisterCodec(t *testing.T) {
+	RegisterCodec("application/vnd.test+json", JSONCodec)
+	defer func() {
+		codecs.Lock()
+		delete(codecs.byType, "application/vnd.test+json")
+		codecs.types = codecs.types[:len(codecs.types)-1]
+		codecs.Unlock()
+	}()
+
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set("Accept", "application/vnd.test+json")
+
+	w := httptest.NewRecorder()
+	if err := (Response{ResponseWriter: w, request: req}).Render(Map{"ok": true}); err != nil {
+		t.Fatal(err)
+	}
+
+	if ct := w.Header().Get("Content-Type"); ct != "application/vnd.test+json" || w.Body.String() != `{"ok":true}` {
+		t.Errorf("unexpected response %s %s", ct, w.Body.String())
+	}
+	if vary := w.Header().Get("Vary"); vary != "Accept" {
+		t.Errorf("expected Vary header, got '%s'", vary)
+	}
+	if w.Result().StatusCode != http.StatusOK {
+		t.Errorf("unexpected status %d", w.Code)
+	}
+}
@@ -0,0 +1,411 @@
+package phi
+
+import (
+	"errors"
+	"fmt"
+	"runtime"
+	"strings"
+)
+
+// registration records a route registered on a Mux and its call site
+type registration struct {
+	method  methodTyp
+	pattern string
+	site    string
+
+	// registered with request predicates, see Matching
+	matched bool
+}
+
+// RouteConflict describes a route conflicting with another one, reported by
+// Strict on registration and by Mux.Validate.
+type RouteConflict struct {
+	// Reason is one of "duplicate", "ambiguous", "unreachable", "wildcard"
+	// or "shadowed".
+	Reason string
+
+	Method  string
+	Pattern string
+	Site    string
+
+	// Other is the pattern of the route conflicting with Pattern
+	Other     string
+	OtherSite string
+}
+
+func (c *RouteConflict) Error() string {
+	route := fmt.Sprintf("%s %s (%s)", c.Method, c.Pattern, c.Site)
+	other := fmt.Sprintf("%s (%s)", c.Other, c.OtherSite)
+
+	switch c.Reason {
+	case "duplicate":
+		return fmt.Sprintf("phi: duplicate route %s, already registered as %s", route, other)
+	case "ambiguous":
+		return fmt.Sprintf("phi: ambiguous route %s overlaps %s", route, other)
+	case "unreachable":
+		return fmt.Sprintf("phi: route %s is unreachable, it is replaced by %s", route, other)
+	case "wildcard":
+		return fmt.Sprintf("phi: wildcards of %s (%s) overlap those of %s with other names", c.Pattern, c.Site, other)
+	case "shadowed":
+		return fmt.Sprintf("phi: route %s is shadowed by %s of a parent router", route, other)
+	}
+	return fmt.Sprintf("phi: route %s conflicts with %s", route, other)
+}
+
+// RouteConflicts lists the conflicts reported by Mux.Validate.
+type RouteConflicts []*RouteConflict
+
+func (cs RouteConflicts) Error() string {
+	msgs := make([]string, 0, len(cs))
+	for _, c := range cs {
+		msgs = append(msgs, c.Error())
+	}
+	return strings.Join(msgs, "\n")
+}
+
+// Strict makes the Mux panic on registration of routes that conflict with
+// routes registered before, instead of silently replacing or shadowing them:
+//
+//	r := phi.NewRouter(phi.Strict())
+//	r.Get("/users/{id}", getUser)
+//	r.Get("/users/{uid}", getUser) // panics
+//
+// Routes are ambiguous if they only differ by the names of their params or
+// the regexps of the same segment. Routes with request predicates, see
+// Matching, are not reported.
+func Strict() Option {
+	return func(mx *Mux) {
+		mx.strict = true
+	}
+}
+
+// CheckConflicts makes the Mux record its routes and where they were
+// registered, so Validate can report conflicting routes. Strict implies it,
+// other routers don't pay for walking the stack on registration.
+func CheckConflicts() Option {
+	return func(mx *Mux) {
+		mx.checkConflicts = true
+	}
+}
+
+// register records the route for conflict detection and reports conflicts
+// with previous routes in strict mode, the tree has to be locked
+func (mx *Mux) register(method methodTyp, pattern string) {
+	m := mx.owner()
+	if !m.recordsRoutes() {
+		return
+	}
+
+	reg := &registration{method: method, pattern: pattern, site: callSite(), matched: len(mx.matchers) > 0}
+
+	if m.strict {
+		if err := checkRegexps(reg); err != nil {
+			panic(err.Error())
+		}
+
+		for _, prev := range m.registrations {
+			if c := ambiguity(prev, reg, ""); c != nil {
+				panic(c.Error())
+			}
+		}
+	}
+
+	m.registrations = append(m.registrations, reg)
+}
+
+// unregister removes the records of routes removed from the tree, the tree
+// has to be locked
+func (mx *Mux) unregister(method methodTyp, pattern string) {
+	shape := patternShape(pattern, true)
+
+	regs := mx.registrations[:0]
+	for _, reg := range mx.registrations {
+		if reg.method&method != 0 && patternShape(reg.pattern, true) == shape {
+			// routes of all methods just lose the removed ones
+			reg.method &^= method
+		}
+		if reg.method != 0 {
+			regs = append(regs, reg)
+		}
+	}
+	mx.registrations = regs
+}
+
+// recordsRoutes reports whether routes are recorded for Validate
+func (mx *Mux) recordsRoutes() bool {
+	return mx.strict || mx.checkConflicts
+}
+
+// Validate reports conflicting routes of the router and its subrouters as
+// RouteConflicts, f.e. in unit tests:
+//
+//   - routes replaced by routes of the same method and pattern, or one only
+//     differing by param names, which are unreachable
+//   - routes only differing by the regexps of params, which are ambiguous
+//   - routes naming the same wildcard segment differently
+//   - routes of subrouters shadowed by routes of parent routers
+//
+// The router has to be created with CheckConflicts or Strict, Validate fails
+// otherwise. Mounted routers created without them are not checked.
+func (mx *Mux) Validate() error {
+	if !mx.owner().recordsRoutes() {
+		return errors.New("phi: Validate needs a router created with phi.CheckConflicts or phi.Strict")
+	}
+
+	var conflicts RouteConflicts
+	mx.owner().validate("", nil, &conflicts)
+
+	if len(conflicts) == 0 {
+		return nil
+	}
+	return conflicts
+}
+
+// mountedMux is a router along the prefix of its routes
+type mountedMux struct {
+	mux    *Mux
+	prefix string
+}
+
+func (mx *Mux) validate(prefix string, parents []mountedMux, conflicts *RouteConflicts) {
+	mx.mu.RLock()
+	regs := append([]*registration{}, mx.registrations...)
+	mx.mu.RUnlock()
+
+	for i, reg := range regs {
+		for _, prev := range regs[:i] {
+			c := ambiguity(prev, reg, prefix)
+			if c != nil && c.Reason == "duplicate" {
+				// the previous route is replaced in the tree
+				c = &RouteConflict{
+					Reason:    "unreachable",
+					Method:    c.Method,
+					Pattern:   c.Other,
+					Site:      c.OtherSite,
+					Other:     c.Pattern,
+					OtherSite: c.Site,
+				}
+			} else if c == nil {
+				c = wildcardOverlap(prev, reg, prefix)
+			}
+
+			if c != nil {
+				*conflicts = append(*conflicts, c)
+			}
+		}
+
+		for _, p := range parents {
+			if c := p.mux.shadows(reg, prefix[len(p.prefix):], prefix); c != nil {
+				*conflicts = append(*conflicts, c)
+				break
+			}
+		}
+	}
+
+	parents = append(parents, mountedMux{mux: mx, prefix: prefix})
+	for _, route := range mx.Routes() {
+		sub, ok := route.SubRoutes.(*Mux)
+		if !ok {
+			continue
+		}
+
+		// routes of other hosts don't conflict with the routes of the mux
+		if route.Host != "" {
+			sub.validate(route.Host+prefix, nil, conflicts)
+			continue
+		}
+
+		sub.validate(prefix+strings.TrimSuffix(route.Pattern, "/*"), parents, conflicts)
+	}
+}
+
+// ambiguity reports the route `reg` if it conflicts with the previous route
+// `prev` of the same mux
+func ambiguity(prev, reg *registration, prefix string) *RouteConflict {
+	if prev.matched || reg.matched || prev.method&reg.method == 0 {
+		return nil
+	}
+	if patternShape(prev.pattern, false) != patternShape(reg.pattern, false) {
+		return nil
+	}
+
+	// routes of the same shape replace each other in the tree, those with
+	// other regexps are added next to each other
+	reason := "ambiguous"
+	if patternShape(prev.pattern, true) == patternShape(reg.pattern, true) {
+		reason = "duplicate"
+	}
+
+	return &RouteConflict{
+		Reason:    reason,
+		Method:    methodString(prev.method & reg.method),
+		Pattern:   prefix + reg.pattern,
+		Site:      reg.site,
+		Other:     prefix + prev.pattern,
+		OtherSite: prev.site,
+	}
+}
+
+// wildcardOverlap reports the route `reg` if it names the params along the
+// same path as the previous route `prev` differently, f.e. /users/{id} and
+// /users/{userID}/orders
+func wildcardOverlap(prev, reg *registration, prefix string) *RouteConflict {
+	p1, p2 := prev.pattern, reg.pattern
+
+	for {
+		typ1, key1, rexpat1, _, ps1, pe1 := patNextSegment(p1)
+		typ2, key2, rexpat2, _, ps2, pe2 := patNextSegment(p2)
+
+		// the params of both routes are on the same tree node up to here
+		if typ1 == ntStatic || typ1 != typ2 || rexpat1 != rexpat2 || p1[:ps1] != p2[:ps2] {
+			return nil
+		}
+
+		if key1 != key2 {
+			return &RouteConflict{
+				Reason:    "wildcard",
+				Method:    methodString(reg.method),
+				Pattern:   prefix + reg.pattern,
+				Site:      reg.site,
+				Other:     prefix + prev.pattern,
+				OtherSite: prev.site,
+			}
+		}
+		p1, p2 = p1[pe1:], p2[pe2:]
+	}
+}
+
+// shadows reports the route `reg` of a subrouter mounted along `prefix` if
+// the mux routes its requests to an endpoint of its own
+func (mx *Mux) shadows(reg *registration, prefix, fullPrefix string) *RouteConflict {
+	path, ok := samplePath(prefix + reg.pattern)
+	if !ok {
+		return nil
+	}
+
+	method := reg.method
+	if methodTypString(method) == "" {
+		method = mGET
+	}
+
+	mx.mu.RLock()
+	defer mx.mu.RUnlock()
+
+	n, eps, h := mx.tree.FindRoute(NewRouteContext(), method, path)
+	if n == nil || h == nil || n.subroutes != nil || (eps[mSTUB] != nil && eps[mSTUB].handler != nil) {
+		return nil
+	}
+
+	c := &RouteConflict{
+		Reason:  "shadowed",
+		Method:  methodString(reg.method),
+		Pattern: fullPrefix + reg.pattern,
+		Site:    reg.site,
+		Other:   strings.TrimSuffix(fullPrefix, prefix) + eps[method].pattern,
+	}
+	for _, other := range mx.registrations {
+		if other.pattern == eps[method].pattern && other.method&method != 0 {
+			c.OtherSite = other.site
+		}
+	}
+
+	return c
+}
+
+// patternShape returns the pattern without the names of its params, and
+// without their regexps unless `regexps` is set
+func patternShape(pattern string, regexps bool) string {
+	var b strings.Builder
+
+	for pattern != "" {
+		typ, _, rexpat, _, ps, pe := patNextSegment(pattern)
+		if typ == ntStatic {
+			b.WriteString(pattern)
+			break
+		}
+		b.WriteString(pattern[:ps])
+		pattern = pattern[pe:]
+
+		switch {
+		case typ == ntCatchAll:
+			b.WriteString("*")
+		case typ == ntRegexp && regexps:
+			b.WriteString("{:" + rexpat + "}")
+		case typ == ntRegexp:
+			b.WriteString("{:}")
+		default:
+			b.WriteString("{}")
+		}
+	}
+
+	return b.String()
+}
+
+// samplePath returns a path matched by the pattern, patterns with regexps
+// are not sampled
+func samplePath(pattern string) (string, bool) {
+	var b strings.Builder
+
+	for pattern != "" {
+		typ, _, _, _, ps, pe := patNextSegment(pattern)
+		if typ == ntStatic {
+			b.WriteString(pattern)
+			break
+		}
+		if typ == ntRegexp {
+			return "", false
+		}
+		b.WriteString(pattern[:ps])
+		b.WriteString("x")
+		pattern = pattern[pe:]
+	}
+
+	return b.String(), true
+}
+
+// checkRegexps reports invalid regexps of the route including its call site
+func checkRegexps(reg *registration) error {
+	pattern := reg.pattern
+	for pattern != "" {
+		typ, _, rexpat, _, _, pe := patNextSegment(pattern)
+		if typ == ntStatic {
+			break
+		}
+		pattern = pattern[pe:]
+
+		if typ != ntRegexp {
+			continue
+		}
+		if _, err := compileRegexp(rexpat); err != nil {
+			return fmt.Errorf("phi: %v in route %s (%s)", err, reg.pattern, reg.site)
+		}
+	}
+
+	return nil
+}
+
+// methodString returns the http method of the method type, or "*" if the
+// type spans several methods
+func methodString(method methodTyp) string {
+	if m := methodTypString(method); m != "" {
+		return m
+	}
+	return "*"
+}
+
+// callSite returns the location of the first caller outside of the phi
+// package, which registered a route
+func callSite() string {
+	pcs := make([]uintptr, 32)
+	frames := runtime.CallersFrames(pcs[:runtime.Callers(3, pcs)])
+
+	for {
+		f, more := frames.Next()
+		if !strings.HasPrefix(f.Function, "go.philip.id/phi.") || strings.HasSuffix(f.File, "_test.go") {
+			return fmt.Sprintf("%s:%d", f.File, f.Line)
+		}
+		if !more {
+			return "unknown"
+		}
+	}
+}
@@ -0,0 +1,130 @@
+package phi
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestStrict(t *testing.T) {
+	h := func(w http.ResponseWriter, r *http.Request) {}
+
+	tests := []struct {
+		name     string
+		register func(r Router)
+		err      string
+	}{
+		{"param names", func(r Router) {
+			r.Get("/users/{id}", h)
+			r.Get("/users/{uid}", h)
+		}, "phi: duplicate route GET /users/{uid} (conflict_test.go:20), already registered as /users/{id} (conflict_test.go:19)"},
+		{"duplicate", func(r Router) {
+			r.Get("/users", h)
+			r.Group(func(r Router) {
+				r.Get("/users", h)
+			})
+		}, "phi: duplicate route GET /users (conflict_test.go:25), already registered as /users (conflict_test.go:23)"},
+		{"all methods", func(r Router) {
+			r.Handle("/users", http.HandlerFunc(h))
+			r.Post("/users", h)
+		}, "phi: duplicate route POST /users (conflict_test.go:30)"},
+		{"regexps", func(r Router) {
+			r.Get("/users/{id:[0-9]+}", h)
+			r.Get("/users/{name:[a-z0-9]+}", h)
+		}, "phi: ambiguous route GET /users/{name:[a-z0-9]+} (conflict_test.go:34) overlaps /users/{id:[0-9]+} (conflict_test.go:33)"},
+		{"subrouter", func(r Router) {
+			r.Route("/api", func(r Router) {
+				r.Get("/{a}/x", h)
+				r.Get("/{b}/x", h)
+			})
+		}, "phi: duplicate route GET /{b}/x (conflict_test.go:39)"},
+		{"invalid regexp", func(r Router) {
+			r.Get("/users/{id:[0-9+}", h)
+		}, "phi: invalid regexp '^[0-9+$': error parsing regexp: missing closing ]: `[0-9+$` in route /users/{id:[0-9+} (conflict_test.go:43)"},
+	}
+
+	for _, tt := range tests {
+		func() {
+			defer func() {
+				rec, _ := recover().(string)
+				if !strings.HasPrefix(strings.ReplaceAll(rec, callSiteDir(), ""), tt.err) {
+					t.Errorf("%s: expected panic %q, got %q", tt.name, tt.err, rec)
+				}
+			}()
+
+			tt.register(NewRouter(Strict()))
+		}()
+	}
+
+	// no conflicts without strict mode, and of routes with predicates
+	r := NewRouter()
+	r.Get("/users/{id}", h)
+	r.Get("/users/{uid}", h)
+
+	r = NewRouter(Strict())
+	r.Matching(Header("X-Version", "2")).Get("/users/{id}", h)
+	r.Get("/users/{id}", h)
+	r.Get("/users/{id}/orders", h)
+	r.Get("/users/{id:[0-9]+}", h)
+	r.Post("/users/{id}", h)
+}
+
+// callSiteDir returns the directory of the test file in call sites
+func callSiteDir() string {
+	site := callSite()
+	return site[:strings.LastIndexByte(site, '/')+1]
+}
+
+func TestValidate(t *testing.T) {
+	h := func(w http.ResponseWriter, r *http.Request) {}
+
+	r := NewRouter(CheckConflicts())
+	r.Get("/users/{id}", h)
+	r.Get("/users/{uid}/orders", h)
+	r.Get("/users/{uid}", h)
+	r.Get("/files/{path:[a-z/]+}", h)
+	r.Get("/files/{name:[a-z]+}", h)
+	r.Get("/admin/users", h)
+	r.Route("/admin", func(r Router) {
+		r.Get("/users", h)
+		r.Get("/stats", h)
+		r.Get("/{page:[a-z]+}", h)
+	})
+	r.Host("{tenant}.example.com", func(r Router) {
+		r.Get("/users/{tenant}", h)
+	})
+
+	err := r.Validate()
+	var conflicts RouteConflicts
+	if !errors.As(err, &conflicts) {
+		t.Fatalf("expected route conflicts, got %v", err)
+	}
+
+	expected := []string{
+		"phi: wildcards of /users/{uid}/orders (conflict_test.go:84) overlap those of /users/{id} (conflict_test.go:83) with other names",
+		"phi: route GET /users/{id} (conflict_test.go:83) is unreachable, it is replaced by /users/{uid} (conflict_test.go:85)",
+		"phi: ambiguous route GET /files/{name:[a-z]+} (conflict_test.go:87) overlaps /files/{path:[a-z/]+} (conflict_test.go:86)",
+		"phi: route GET /admin/users (conflict_test.go:90) is shadowed by /admin/users (conflict_test.go:88) of a parent router",
+	}
+
+	got := strings.Split(strings.ReplaceAll(err.Error(), callSiteDir(), ""), "\n")
+	if strings.Join(got, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("expected conflicts:\n%s\ngot:\n%s", strings.Join(expected, "\n"), strings.Join(got, "\n"))
+	}
+
+	r.Remove("GET", "/users/{id}")
+	r.Remove("GET", "/files/{name:[a-z]+}")
+	r.Remove("GET", "/admin/users")
+	r.Remove("GET", "/users/{uid}/orders")
+	if err := r.Validate(); err != nil {
+		t.Errorf("unexpected conflicts %v", err)
+	}
+
+	// routes are only recorded when checking conflicts
+	r = NewRouter()
+	r.Get("/users/{id}", h)
+	if len(r.registrations) != 0 || r.Validate() == nil {
+		t.Errorf("expected routes not to be recorded, got %d", len(r.registrations))
+	}
+}
@@ -63,6 +63,11 @@ type Context struct {
 	// intentionally unexported so it cant be tampered.
 	routeParams RouteParams
 
+	// Converted values of typed URL parameters, see Param, and those
+	// converted for the current sub-router
+	typedParams      []typedParam
+	routeTypedParams []typedParam
+
 	// The endpoint routing pattern that matched the request URI path
 	// or `RoutePath` of the current sub-router. This value will update
 	// during the lifecycle of a request passing through a stack of
@@ -76,6 +81,26 @@ type Context struct {
 
 	// methodNotAllowed hint
 	methodNotAllowed bool
+
+	// problemDetails is set if the current router responds with RFC 7807
+	// problem details, see Mux#ProblemDetails
+	problemDetails bool
+
+	// errorHandler of the current router, see Mux#ErrorHandler
+	errorHandler ErrorHandlerFunc
+
+	// envelope of the current router or route, see Mux#Envelope
+	envelope Envelope
+
+	// requestIDFunc of the current router, see Mux#RequestIDMeta
+	requestIDFunc func(ctx context.Context) string
+
+	// decodeOptions of the current router, see Mux#Decoding
+	decodeOptions *DecodeOptions
+
+	// meta data and links of the response written into its envelope
+	meta  Map
+	links Map
 }
 
 // Reset a routing context to its initial state.
@@ -90,7 +115,16 @@ func (x *Context) Reset() {
 	x.routePattern = ""
 	x.routeParams.Keys = x.routeParams.Keys[:0]
 	x.routeParams.Values = x.routeParams.Values[:0]
+	x.typedParams = x.typedParams[:0]
+	x.routeTypedParams = x.routeTypedParams[:0]
 	x.methodNotAllowed = false
+	x.problemDetails = false
+	x.errorHandler = nil
+	x.envelope = nil
+	x.requestIDFunc = nil
+	x.decodeOptions = nil
+	x.meta = nil
+	x.links = nil
 	x.parentCtx = nil
 }
 
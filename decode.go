@@ -0,0 +1,163 @@
+package phi
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// DecodeOptions harden the decoding of request bodies by Validate and Bind,
+// see Mux.Decoding
+type DecodeOptions struct {
+	// MaxBytes is the maximum size of request bodies, unlimited if zero.
+	// Larger bodies result in RequestTooLargeError.
+	MaxBytes int64
+
+	// DisallowUnknownFields rejects json bodies with fields the target
+	// struct doesn't have, other codecs ignore it
+	DisallowUnknownFields bool
+
+	// MaxDepth is the maximum nesting depth of json arrays and objects,
+	// unlimited if zero, other codecs ignore it
+	MaxDepth int
+
+	// UseNumber decodes json numbers into interface{} values as json.Number
+	// instead of float64
+	UseNumber bool
+}
+
+// Decoding sets the options decoding request bodies with Validate and Bind
+// for the router and its subrouters:
+//
+//	r.Decoding(phi.DecodeOptions{
+//		MaxBytes:              1 << 20,
+//		DisallowUnknownFields: true,
+//		MaxDepth:              32,
+//	})
+//
+// Bodies exceeding MaxBytes result in errors with status 413, unknown
+// fields and too deeply nested bodies in errors with status 400. Use
+// middleware.BodyLimit to limit bodies of other handlers as well.
+//
+// DisallowUnknownFields, MaxDepth and UseNumber only apply to json bodies,
+// bodies of other codecs, see Bind, are only limited by MaxBytes.
+func (mx *Mux) Decoding(o DecodeOptions) {
+	m := mx
+	if mx.inline && mx.parent != nil {
+		m = mx.parent
+	}
+
+	// Update the decode options from this point forward
+	m.decodeOptions = &o
+	m.updateSubRoutes(func(subMux *Mux) {
+		if subMux.decodeOptions == nil {
+			subMux.Decoding(o)
+		}
+	})
+}
+
+// decodeBody decodes the request body with the codec and the decode options
+// of the router, json bodies must not be followed by further data
+func decodeBody(r *Request, codec Codec, v interface{}) *Error {
+	var o DecodeOptions
+	if rctx := RouteContext(r.Context()); rctx != nil && rctx.decodeOptions != nil {
+		o = *rctx.decodeOptions
+	}
+
+	body := &limitedBody{ReadCloser: r.Body, remaining: o.MaxBytes}
+	var content io.Reader = r.Body
+	if o.MaxBytes > 0 {
+		content = body
+	}
+
+	if _, ok := codec.(jsonCodec); !ok {
+		if err := codec.Decode(content, v); err != nil {
+			return decodeError(body, err)
+		}
+		return nil
+	}
+
+	if o.MaxDepth > 0 {
+		data, err := io.ReadAll(content)
+		if err != nil {
+			return decodeError(body, err)
+		}
+		if depth := jsonDepth(data); depth > o.MaxDepth {
+			return InvalidBodyParameterError(fmt.Sprintf("nesting depth %d exceeds the maximum of %d", depth, o.MaxDepth))
+		}
+		content = bytes.NewReader(data)
+	}
+
+	dec := json.NewDecoder(content)
+	if o.DisallowUnknownFields {
+		dec.DisallowUnknownFields()
+	}
+	if o.UseNumber {
+		dec.UseNumber()
+	}
+
+	if err := dec.Decode(v); err != nil {
+		return decodeError(body, err)
+	}
+
+	if _, err := dec.Token(); err != io.EOF {
+		if body.tooLarge(err) {
+			return decodeError(body, err)
+		}
+		return InvalidBodyParameterError("unexpected data after the json value")
+	}
+
+	return nil
+}
+
+// decodeError returns the error of failing to decode the body
+func decodeError(body *limitedBody, err error) *Error {
+	if body.tooLarge(err) {
+		return RequestTooLargeError("request body too large")
+	}
+
+	if key := strings.TrimPrefix(err.Error(), "json: unknown field "); key != err.Error() {
+		if unquoted, err := strconv.Unquote(key); err == nil {
+			key = unquoted
+		}
+
+		return InvalidBodyParameterError(fmt.Sprintf("unknown field '%s'", key)).WithDetails(FieldError{
+			Path:    key,
+			Rule:    "unknown",
+			Message: "is not allowed",
+		})
+	}
+
+	return &decodingError
+}
+
+// jsonDepth returns the maximum nesting depth of arrays and objects of the
+// json document
+func jsonDepth(data []byte) int {
+	depth, max := 0, 0
+	inString, escaped := false, false
+
+	for _, c := range data {
+		switch {
+		case escaped:
+			escaped = false
+		case inString && c == '\\':
+			escaped = true
+		case c == '"':
+			inString = !inString
+		case inString:
+		case c == '{' || c == '[':
+			depth++
+			if depth > max {
+				max = depth
+			}
+		case c == '}' || c == ']':
+			depth--
+		}
+	}
+
+	return max
+}
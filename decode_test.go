@@ -0,0 +1,73 @@
+package phi
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestDecoding(t *testing.T) {
+	type body struct {
+		Name  string      `json:"name"`
+		Extra interface{} `json:"extra"`
+	}
+
+	handler := func(w *Response, r *Request) *Error {
+		b, err := Bind[body](r)
+		if err != nil {
+			return err
+		}
+		if _, ok := b.Extra.(float64); ok {
+			return &Error{Error: "noJSONNumber", StatusCode: 500}
+		}
+		return w.JSON(b.Name)
+	}
+
+	r := NewRouter()
+	r.Route("/api", func(r Router) {
+		r.POST("/", handler)
+	})
+	r.Decoding(DecodeOptions{MaxBytes: 64, DisallowUnknownFields: true, MaxDepth: 3, UseNumber: true})
+
+	tests := []struct {
+		path   string
+		body   string
+		status int
+		msg    string
+	}{
+		{"/api/", `{"name":"phi","extra":1}`, 200, ""},
+		{"/api/", `{"name":"phi","extra":[[1]]}`, 200, ""},
+		{"/api/", `{"name":"phi","extra":[[[1]]]}`, 400, "nesting depth 4 exceeds the maximum of 3"},
+		{"/api/", `{"name":"phi","admin":true}`, 400, "unknown field 'admin'"},
+		{"/api/", `{"name":"phi"} {"name":"other"}`, 400, "unexpected data after the json value"},
+		{"/api/", `{"name":"` + strings.Repeat("x", 64) + `"}`, 413, ""},
+		{"/api/", `{"name":"phi","extra":"[[[[1]]]]"}`, 200, ""},
+	}
+
+	for _, tt := range tests {
+		resp, respBody := testHandler(t, r, "POST", tt.path, strings.NewReader(tt.body))
+		if resp.StatusCode != tt.status {
+			t.Errorf("%s: expected status %d, got %d: %s", tt.body, tt.status, resp.StatusCode, respBody)
+		}
+		if tt.msg != "" && !strings.Contains(respBody, tt.msg) {
+			t.Errorf("%s: expected message %q, got %s", tt.body, tt.msg, respBody)
+		}
+	}
+
+	// without decode options only trailing data is rejected
+	r = NewRouter()
+	r.POST("/", handler)
+	for body, status := range map[string]int{
+		`{"name":"phi","admin":true}`:       200,
+		`{"name":"phi"}` + "\n":             200,
+		`{"name":"phi"}x`:                   400,
+		`{"name":"phi","extra":[[[[1]]]]}`:  200,
+		`{"name":"phi","extra":1}`:          500,
+		fmt.Sprintf(`{"name":"%0100d"}`, 0): 200,
+	} {
+		resp, respBody := testHandler(t, r, "POST", "/", strings.NewReader(body))
+		if resp.StatusCode != status {
+			t.Errorf("%s: expected status %d, got %d: %s", body, status, resp.StatusCode, respBody)
+		}
+	}
+}
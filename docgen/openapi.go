@@ -0,0 +1,402 @@
+// Package docgen generates documentation for phi routers.
+//
+// OpenAPI walks a phi.Routes tree and builds an OpenAPI 3.1 document out of
+// the registered routes. URL params, including their regexp constraints, are
+// turned into path parameters and request/response schemas are inferred for
+// handlers implementing phi.TypedHandler, f.e. those registered by phi.Post.
+// Scopes, roles and policies of guarding middlewares like
+// middleware.RequireScopes are documented as security requirements.
+//
+// Example:
+//
+//	doc, err := docgen.OpenAPI(r, docgen.Info{Title: "API", Version: "1.0.0"})
+//	if err != nil {
+//		log.Fatal(err)
+//	}
+//
+//	out, _ := doc.YAML()
+//	os.WriteFile("openapi.yaml", out, 0o644)
+package docgen
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+
+	"go.philip.id/phi"
+)
+
+// Version is the OpenAPI specification version of generated documents.
+const Version = "3.1.0"
+
+// BearerScheme is the name of the security scheme of operations requiring
+// scopes, roles or policies.
+const BearerScheme = "bearerAuth"
+
+// Document is the root object of an OpenAPI document.
+type Document struct {
+	OpenAPI    string               `json:"openapi"`
+	Info       Info                 `json:"info"`
+	Servers    []Server             `json:"servers,omitempty"`
+	Paths      map[string]*PathItem `json:"paths"`
+	Components *Components          `json:"components,omitempty"`
+}
+
+// Info provides metadata about the API.
+type Info struct {
+	Title       string `json:"title"`
+	Version     string `json:"version"`
+	Summary     string `json:"summary,omitempty"`
+	Description string `json:"description,omitempty"`
+}
+
+// Server describes a server hosting the API.
+type Server struct {
+	URL         string `json:"url"`
+	Description string `json:"description,omitempty"`
+}
+
+// PathItem describes the operations available on a single path.
+type PathItem struct {
+	Get     *Operation `json:"get,omitempty"`
+	Put     *Operation `json:"put,omitempty"`
+	Post    *Operation `json:"post,omitempty"`
+	Delete  *Operation `json:"delete,omitempty"`
+	Options *Operation `json:"options,omitempty"`
+	Head    *Operation `json:"head,omitempty"`
+	Patch   *Operation `json:"patch,omitempty"`
+	Trace   *Operation `json:"trace,omitempty"`
+
+	Parameters []*Parameter `json:"parameters,omitempty"`
+}
+
+// Operation describes a single API operation on a path.
+type Operation struct {
+	OperationID string               `json:"operationId,omitempty"`
+	Summary     string               `json:"summary,omitempty"`
+	Parameters  []*Parameter         `json:"parameters,omitempty"`
+	RequestBody *RequestBody         `json:"requestBody,omitempty"`
+	Responses   map[string]*Response `json:"responses,omitempty"`
+
+	// Security holds the scopes required for the operation
+	Security []SecurityRequirement `json:"security,omitempty"`
+
+	// Roles and Policies are required besides the scopes, see phi.Requirements
+	Roles    []string `json:"x-roles,omitempty"`
+	Policies []string `json:"x-policies,omitempty"`
+}
+
+// SecurityRequirement maps names of security schemes to the scopes required.
+type SecurityRequirement map[string][]string
+
+// SecurityScheme describes a security scheme of the API.
+type SecurityScheme struct {
+	Type         string `json:"type"`
+	Scheme       string `json:"scheme,omitempty"`
+	BearerFormat string `json:"bearerFormat,omitempty"`
+}
+
+// Parameter describes a single operation parameter.
+type Parameter struct {
+	Name        string  `json:"name"`
+	In          string  `json:"in"`
+	Description string  `json:"description,omitempty"`
+	Required    bool    `json:"required,omitempty"`
+	Schema      *Schema `json:"schema,omitempty"`
+}
+
+// RequestBody describes a single request body.
+type RequestBody struct {
+	Description string                `json:"description,omitempty"`
+	Required    bool                  `json:"required,omitempty"`
+	Content     map[string]*MediaType `json:"content"`
+}
+
+// Response describes a single response of an operation.
+type Response struct {
+	Description string                `json:"description"`
+	Content     map[string]*MediaType `json:"content,omitempty"`
+}
+
+// MediaType provides the schema for a content type.
+type MediaType struct {
+	Schema *Schema `json:"schema,omitempty"`
+}
+
+// Components holds reusable objects of the document.
+type Components struct {
+	Schemas         map[string]*Schema         `json:"schemas,omitempty"`
+	SecuritySchemes map[string]*SecurityScheme `json:"securitySchemes,omitempty"`
+}
+
+// JSON returns the document encoded as indented JSON.
+func (d *Document) JSON() ([]byte, error) {
+	return json.MarshalIndent(d, "", "  ")
+}
+
+// YAML returns the document encoded as YAML.
+func (d *Document) YAML() ([]byte, error) {
+	data, err := json.Marshal(d)
+	if err != nil {
+		return nil, err
+	}
+
+	return jsonToYAML(data)
+}
+
+// OpenAPI walks the routes of r and builds an OpenAPI document from them.
+// Routes of phi.Mux.Host subrouters are documented without their host, it
+// fails if routes of different hosts share a method and path.
+func OpenAPI(r phi.Routes, info Info) (*Document, error) {
+	doc := &Document{
+		OpenAPI: Version,
+		Info:    info,
+		Paths:   map[string]*PathItem{},
+	}
+	schemas := newSchemaRegistry()
+	secured := false
+
+	// hosts of the documented operations by method and path
+	hosts := map[string]string{}
+
+	err := phi.WalkRequirements(r, func(method string, route string, handler http.Handler, rq phi.Requirements) error {
+		// routes of phi.Mux.Host subrouters are prefixed by their host
+		host := ""
+		if idx := strings.IndexByte(route, '/'); idx > 0 {
+			host, route = route[:idx], route[idx:]
+		}
+
+		path, params := convertPattern(route)
+
+		// operations of different hosts can't share a path of the document
+		key := method + " " + path
+		if other, ok := hosts[key]; ok && other != host {
+			return fmt.Errorf("docgen: %s is routed for hosts '%s' and '%s'", key, other, host)
+		}
+		hosts[key] = host
+
+		item, ok := doc.Paths[path]
+		if !ok {
+			item = &PathItem{}
+			doc.Paths[path] = item
+		}
+
+		op := &Operation{
+			OperationID: operationID(method, path),
+			Parameters:  params,
+		}
+
+		if th, ok := handler.(phi.TypedHandler); ok {
+			if in := th.RequestType(); in != nil {
+				op.RequestBody = &RequestBody{
+					Required: true,
+					Content: map[string]*MediaType{
+						"application/json": {Schema: schemas.schema(in)},
+					},
+				}
+			}
+
+			if out := th.ResponseType(); out != nil {
+				op.Responses = map[string]*Response{
+					"200": {
+						Description: http.StatusText(http.StatusOK),
+						Content: map[string]*MediaType{
+							"application/json": {Schema: dataEnvelope(schemas.schema(out))},
+						},
+					},
+				}
+			}
+		}
+
+		if !rq.Empty() {
+			scopes := rq.Scopes
+			if scopes == nil {
+				scopes = []string{}
+			}
+			op.Security = []SecurityRequirement{{BearerScheme: scopes}}
+			op.Roles, op.Policies = rq.Roles, rq.Policies
+			secured = true
+		}
+
+		return item.set(method, op)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if len(schemas.defs) > 0 || secured {
+		doc.Components = &Components{Schemas: schemas.defs}
+	}
+	if secured {
+		doc.Components.SecuritySchemes = map[string]*SecurityScheme{
+			BearerScheme: {Type: "http", Scheme: "bearer", BearerFormat: "JWT"},
+		}
+	}
+
+	return doc, nil
+}
+
+// set assigns the operation for the http method on the path item, other
+// methods are skipped.
+func (p *PathItem) set(method string, op *Operation) error {
+	switch method {
+	case http.MethodGet:
+		p.Get = op
+	case http.MethodPut:
+		p.Put = op
+	case http.MethodPost:
+		p.Post = op
+	case http.MethodDelete:
+		p.Delete = op
+	case http.MethodOptions:
+		p.Options = op
+	case http.MethodHead:
+		p.Head = op
+	case http.MethodPatch:
+		p.Patch = op
+	case http.MethodTrace:
+		p.Trace = op
+	default:
+		// CONNECT and custom methods of phi.RegisterMethod are not
+		// representable in OpenAPI
+	}
+
+	return nil
+}
+
+// convertPattern turns a phi routing pattern into an OpenAPI path template
+// and the path parameters used within.
+//
+//	/users/{id:\\d+}/* -> /users/{id}/{wildcard}
+func convertPattern(pattern string) (string, []*Parameter) {
+	var (
+		path   strings.Builder
+		params []*Parameter
+	)
+
+	for len(pattern) > 0 {
+		ps := strings.IndexByte(pattern, '{')
+		ws := strings.IndexByte(pattern, '*')
+
+		if ps < 0 && ws < 0 {
+			path.WriteString(pattern)
+			break
+		}
+
+		// catch-all is always the last segment of a route
+		if ps < 0 || (ws >= 0 && ws < ps) {
+			path.WriteString(pattern[:ws])
+			path.WriteString("{wildcard}")
+			params = append(params, &Parameter{
+				Name:        "wildcard",
+				In:          "path",
+				Description: "Remaining path of the request",
+				Required:    true,
+				Schema:      &Schema{Type: "string"},
+			})
+			break
+		}
+
+		path.WriteString(pattern[:ps])
+
+		// read to closing } taking nested braces of the regexp into account
+		cc, pe := 0, ps
+		for i, c := range pattern[ps:] {
+			if c == '{' {
+				cc++
+			} else if c == '}' {
+				cc--
+				if cc == 0 {
+					pe = ps + i
+					break
+				}
+			}
+		}
+
+		key, rexpat := pattern[ps+1:pe], ""
+		if idx := strings.IndexByte(key, ':'); idx >= 0 {
+			key, rexpat = key[:idx], key[idx+1:]
+		}
+		if key == "" {
+			key = fmt.Sprintf("param%d", len(params)+1)
+		}
+
+		schema := &Schema{Type: "string"}
+		if c, ok := phi.LookupConverter(rexpat); ok {
+			// typed params, f.e. {id:int}
+			switch rexpat {
+			case "int":
+				schema, rexpat = &Schema{Type: "integer"}, ""
+			case "uuid", "date":
+				schema.Format, rexpat = rexpat, ""
+			default:
+				rexpat = c.Regexp
+			}
+		}
+		if rexpat != "" {
+			if rexpat[0] != '^' {
+				rexpat = "^" + rexpat
+			}
+			if rexpat[len(rexpat)-1] != '$' {
+				rexpat += "$"
+			}
+			schema.Pattern = rexpat
+		}
+
+		path.WriteString("{" + key + "}")
+		params = append(params, &Parameter{
+			Name:     key,
+			In:       "path",
+			Required: true,
+			Schema:   schema,
+		})
+
+		pattern = pattern[pe+1:]
+	}
+
+	return path.String(), params
+}
+
+// operationID builds a stable identifier out of method and path, f.e.
+// GET /users/{id} -> getUsersId
+func operationID(method, path string) string {
+	var b strings.Builder
+	b.WriteString(strings.ToLower(method))
+
+	upper := true
+	for _, c := range path {
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9':
+			if upper && c >= 'a' && c <= 'z' {
+				c -= 'a' - 'A'
+			}
+			b.WriteRune(c)
+			upper = false
+		default:
+			upper = true
+		}
+	}
+
+	return b.String()
+}
+
+// dataEnvelope wraps the schema into the { "data": <schema> } object written
+// by phi.Response.JSON.
+func dataEnvelope(s *Schema) *Schema {
+	return &Schema{
+		Type:       "object",
+		Properties: map[string]*Schema{"data": s},
+		Required:   []string{"data"},
+	}
+}
+
+// indirect dereferences pointer types.
+func indirect(t reflect.Type) reflect.Type {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	return t
+}
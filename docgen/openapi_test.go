@@ -0,0 +1,219 @@
+package docgen
+
+import (
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+
+	"go.philip.id/phi"
+	"go.philip.id/phi/middleware"
+)
+
+type createUser struct {
+	Name  string   `json:"name,required"`
+	Email string   `json:"email" validate:"required,email"`
+	Age   int      `json:"age" validate:"min=18"`
+	Tags  []string `json:"tags" validate:"max=5,dive,oneof=a b"`
+	Owner *user    `json:"owner"`
+}
+
+type user struct {
+	ID     int    `json:"id"`
+	Name   string `json:"name"`
+	Parent *user  `json:"parent"`
+}
+
+func testRouter() *phi.Mux {
+	r := phi.NewRouter()
+
+	r.GET("/", func(w *phi.Response, r *phi.Request) *phi.Error {
+		return w.JSON("root")
+	})
+
+	r.Route("/users", func(r phi.Router) {
+		r.Method("POST", "/", phi.Validated(func(w *phi.Response, r *phi.Request, body *createUser) *phi.Error {
+			return w.JSON(body)
+		}))
+
+		r.Route("/{id:\\d+}", func(r phi.Router) {
+			r.Get("/", func(w http.ResponseWriter, r *http.Request) {})
+			r.With(middleware.RequireScopes("orders:write"), middleware.RequireRoles("admin")).
+				Delete("/orders/{orderID}", func(w http.ResponseWriter, r *http.Request) {})
+		})
+	})
+
+	r.Mount("/static", http.FileServer(http.Dir(".")))
+
+	r.Host("{tenant}.example.com", func(r phi.Router) {
+		r.Get("/tenant", func(w http.ResponseWriter, r *http.Request) {})
+	})
+
+	return r
+}
+
+func TestOpenAPI(t *testing.T) {
+	doc, err := OpenAPI(testRouter(), Info{Title: "test", Version: "1.0.0"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if doc.OpenAPI != Version {
+		t.Errorf("expected version %s got %s", Version, doc.OpenAPI)
+	}
+
+	for _, p := range []string{"/", "/users/", "/users/{id}/", "/users/{id}/orders/{orderID}", "/static/{wildcard}", "/tenant"} {
+		if doc.Paths[p] == nil {
+			t.Errorf("expected path '%s' to be documented", p)
+		}
+	}
+
+	get := doc.Paths["/users/{id}/"].Get
+	if get == nil || len(get.Parameters) != 1 {
+		t.Fatalf("expected GET /users/{id}/ with one parameter, got %+v", get)
+	}
+	if p := get.Parameters[0]; p.Name != "id" || p.In != "path" || !p.Required || p.Schema.Pattern != "^\\d+$" {
+		t.Errorf("unexpected id parameter %+v %+v", p, p.Schema)
+	}
+
+	del := doc.Paths["/users/{id}/orders/{orderID}"].Delete
+	if del == nil || len(del.Parameters) != 2 || del.Parameters[1].Name != "orderID" || del.Parameters[1].Schema.Pattern != "" {
+		t.Errorf("unexpected DELETE operation %+v", del)
+	}
+
+	if len(del.Security) != 1 || !reflect.DeepEqual(del.Security[0][BearerScheme], []string{"orders:write"}) || !reflect.DeepEqual(del.Roles, []string{"admin"}) {
+		t.Errorf("unexpected security of DELETE operation %+v %v", del.Security, del.Roles)
+	}
+	if get.Security != nil || doc.Components.SecuritySchemes[BearerScheme] == nil {
+		t.Errorf("expected only guarded operations to require the bearer scheme")
+	}
+
+	post := doc.Paths["/users/"].Post
+	if post == nil || post.RequestBody == nil {
+		t.Fatalf("expected POST /users/ to have a request body")
+	}
+	if ref := post.RequestBody.Content["application/json"].Schema.Ref; ref != "#/components/schemas/createUser" {
+		t.Errorf("unexpected request schema ref '%s'", ref)
+	}
+
+	body := doc.Components.Schemas["createUser"]
+	if body == nil || len(body.Required) != 2 || body.Required[0] != "name" || body.Required[1] != "email" {
+		t.Fatalf("unexpected createUser schema %+v", body)
+	}
+	if email := body.Properties["email"]; email.Format != "email" {
+		t.Errorf("unexpected email schema %+v", email)
+	}
+	if age := body.Properties["age"]; age.Minimum == nil || *age.Minimum != 18 || age.Maximum != nil {
+		t.Errorf("unexpected age schema %+v", age)
+	}
+	if tags := body.Properties["tags"]; tags.Type != "array" || tags.Items.Type != "string" || *tags.MaxItems != 5 || len(tags.Items.Enum) != 2 {
+		t.Errorf("unexpected tags schema %+v", tags)
+	}
+	if owner := body.Properties["owner"]; owner.Ref != "#/components/schemas/user" {
+		t.Errorf("unexpected owner schema %+v", owner)
+	}
+	if parent := doc.Components.Schemas["user"].Properties["parent"]; parent.Ref != "#/components/schemas/user" {
+		t.Errorf("unexpected recursive schema %+v", parent)
+	}
+}
+
+func TestOpenAPICustomMethod(t *testing.T) {
+	phi.RegisterMethod("LINK")
+
+	r := phi.NewRouter()
+	r.Get("/users", func(w http.ResponseWriter, r *http.Request) {})
+	r.Method("LINK", "/users", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	doc, err := OpenAPI(r, Info{Title: "test", Version: "1.0.0"})
+	if err != nil {
+		t.Fatalf("expected custom methods to be skipped, got %v", err)
+	}
+	if doc.Paths["/users"] == nil || doc.Paths["/users"].Get == nil {
+		t.Errorf("expected GET /users to be documented")
+	}
+}
+
+func TestOpenAPIHostCollision(t *testing.T) {
+	r := phi.NewRouter()
+	r.Host("a.example.com", func(r phi.Router) {
+		r.Get("/", func(w http.ResponseWriter, r *http.Request) {})
+	})
+	r.Host("b.example.com", func(r phi.Router) {
+		r.Get("/", func(w http.ResponseWriter, r *http.Request) {})
+		r.Post("/", func(w http.ResponseWriter, r *http.Request) {})
+	})
+
+	if _, err := OpenAPI(r, Info{Title: "test", Version: "1.0.0"}); err == nil || !strings.Contains(err.Error(), "GET /") {
+		t.Errorf("expected hosts sharing GET / to collide, got %v", err)
+	}
+}
+
+func TestOpenAPIEncoding(t *testing.T) {
+	doc, err := OpenAPI(testRouter(), Info{Title: "test", Version: "1.0.0"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	js, err := doc.JSON()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !json.Valid(js) {
+		t.Fatalf("invalid json document")
+	}
+
+	yml, err := doc.YAML()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, line := range []string{
+		"openapi: \"3.1.0\"\n",
+		"  \"/users/{id}/\":\n",
+		"        - name: \"id\"\n",
+		"            pattern: \"^\\\\d+$\"\n",
+		"              $ref: \"#/components/schemas/createUser\"\n",
+		"            - \"orders:write\"\n",
+	} {
+		if !strings.Contains(string(yml), line) {
+			t.Errorf("expected yaml to contain %q\n%s", line, yml)
+		}
+	}
+}
+
+func TestConvertPattern(t *testing.T) {
+	tests := []struct {
+		pattern string
+		path    string
+		params  []string
+	}{
+		{"/", "/", nil},
+		{"/users/{id}", "/users/{id}", []string{"id"}},
+		{"/date/{yyyy:\\d\\d\\d\\d}/{mm:\\d{2}}", "/date/{yyyy}/{mm}", []string{"yyyy", "mm"}},
+		{"/files/{:[a-z]+}.json", "/files/{param1}.json", []string{"param1"}},
+		{"/admin/*", "/admin/{wildcard}", []string{"wildcard"}},
+		{"/orders/{id:int}/{day:date}", "/orders/{id}/{day}", []string{"id", "day"}},
+	}
+
+	for _, tt := range tests {
+		path, params := convertPattern(tt.pattern)
+		if path != tt.path {
+			t.Errorf("%s: expected path %s got %s", tt.pattern, tt.path, path)
+		}
+		if len(params) != len(tt.params) {
+			t.Errorf("%s: expected %d params got %d", tt.pattern, len(tt.params), len(params))
+			continue
+		}
+		for i, p := range params {
+			if p.Name != tt.params[i] {
+				t.Errorf("%s: expected param %s got %s", tt.pattern, tt.params[i], p.Name)
+			}
+		}
+	}
+
+	_, params := convertPattern("/orders/{id:int}/{day:date}/{slug:slug}")
+	if params[0].Schema.Type != "integer" || params[1].Schema.Format != "date" || params[2].Schema.Pattern != "^[a-z0-9]+(?:-[a-z0-9]+)*$" {
+		t.Errorf("unexpected schemas of typed params %+v %+v %+v", params[0].Schema, params[1].Schema, params[2].Schema)
+	}
+}
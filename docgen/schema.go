@@ -0,0 +1,259 @@
+package docgen
+
+import (
+	"encoding"
+	"encoding/json"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Schema is a JSON Schema (draft 2020-12) object as used by OpenAPI 3.1.
+type Schema struct {
+	Ref                  string             `json:"$ref,omitempty"`
+	Type                 string             `json:"type,omitempty"`
+	Format               string             `json:"format,omitempty"`
+	Pattern              string             `json:"pattern,omitempty"`
+	Enum                 []string           `json:"enum,omitempty"`
+	Minimum              *float64           `json:"minimum,omitempty"`
+	Maximum              *float64           `json:"maximum,omitempty"`
+	MinLength            *int               `json:"minLength,omitempty"`
+	MaxLength            *int               `json:"maxLength,omitempty"`
+	MinItems             *int               `json:"minItems,omitempty"`
+	MaxItems             *int               `json:"maxItems,omitempty"`
+	ContentEncoding      string             `json:"contentEncoding,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	AdditionalProperties *Schema            `json:"additionalProperties,omitempty"`
+}
+
+var (
+	timeType          = reflect.TypeOf(time.Time{})
+	rawMessageType    = reflect.TypeOf(json.RawMessage{})
+	textMarshalerType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+)
+
+// schemaRegistry builds schemas for Go types, named struct types are
+// registered once as components and referenced from everywhere else.
+type schemaRegistry struct {
+	defs  map[string]*Schema
+	names map[reflect.Type]string
+}
+
+func newSchemaRegistry() *schemaRegistry {
+	return &schemaRegistry{
+		defs:  map[string]*Schema{},
+		names: map[reflect.Type]string{},
+	}
+}
+
+// schema returns the schema describing values of type t.
+func (s *schemaRegistry) schema(t reflect.Type) *Schema {
+	t = indirect(t)
+
+	switch t {
+	case timeType:
+		return &Schema{Type: "string", Format: "date-time"}
+	case rawMessageType:
+		return &Schema{}
+	}
+
+	if reflect.PtrTo(t).Implements(textMarshalerType) {
+		return &Schema{Type: "string"}
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return &Schema{Type: "integer", Format: "int32"}
+	case reflect.Int64, reflect.Uint64, reflect.Uintptr:
+		return &Schema{Type: "integer", Format: "int64"}
+	case reflect.Float32:
+		return &Schema{Type: "number", Format: "float"}
+	case reflect.Float64:
+		return &Schema{Type: "number", Format: "double"}
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return &Schema{Type: "string", ContentEncoding: "base64"}
+		}
+
+		return &Schema{Type: "array", Items: s.schema(t.Elem())}
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: s.schema(t.Elem())}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return s.structSchema(t)
+		}
+
+		return &Schema{Ref: "#/components/schemas/" + s.define(t)}
+	}
+
+	// interfaces, funcs and channels allow any value
+	return &Schema{}
+}
+
+// define registers the named struct type t as component and returns its name.
+func (s *schemaRegistry) define(t reflect.Type) string {
+	if name, ok := s.names[t]; ok {
+		return name
+	}
+
+	name := componentName(t.Name())
+	if _, taken := s.defs[name]; taken {
+		name = componentName(t.PkgPath() + "." + t.Name())
+	}
+
+	// register before resolving the fields to support recursive types
+	s.names[t] = name
+	s.defs[name] = &Schema{}
+	*s.defs[name] = *s.structSchema(t)
+
+	return name
+}
+
+// structSchema builds an object schema of the exported fields of t.
+func (s *schemaRegistry) structSchema(t reflect.Type) *Schema {
+	schema := &Schema{Type: "object", Properties: map[string]*Schema{}}
+	s.addFields(schema, t)
+
+	return schema
+}
+
+func (s *schemaRegistry) addFields(schema *Schema, t reflect.Type) {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+
+		tag := f.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+
+		name, opts, _ := strings.Cut(tag, ",")
+
+		// embedded structs without a name are flattened like encoding/json does
+		if f.Anonymous && name == "" && indirect(f.Type).Kind() == reflect.Struct {
+			s.addFields(schema, indirect(f.Type))
+			continue
+		}
+
+		if !f.IsExported() {
+			continue
+		}
+
+		if name == "" {
+			name = f.Name
+		}
+
+		prop := s.schema(f.Type)
+		schema.Properties[name] = prop
+
+		required := false
+		for _, opt := range strings.Split(opts, ",") {
+			if opt == "required" {
+				required = true
+			}
+		}
+		if applyRules(prop, f.Tag.Get("validate")) {
+			required = true
+		}
+
+		if required {
+			schema.Required = append(schema.Required, name)
+		}
+	}
+}
+
+// applyRules maps the rules of a phi `validate:"..."` tag onto the schema
+// and reports whether the field is required. Rules following `dive`
+// describe the items of arrays and maps.
+func applyRules(schema *Schema, tag string) bool {
+	required := false
+
+	for tag != "" {
+		var rule string
+		if strings.HasPrefix(tag, "regexp=") {
+			rule, tag = tag, ""
+		} else {
+			rule, tag, _ = strings.Cut(tag, ",")
+		}
+
+		name, param, _ := strings.Cut(strings.TrimSpace(rule), "=")
+		switch name {
+		case "required":
+			required = true
+		case "dive":
+			if schema.Items != nil {
+				applyRules(schema.Items, tag)
+			} else if schema.AdditionalProperties != nil {
+				applyRules(schema.AdditionalProperties, tag)
+			}
+			return required
+		case "email":
+			schema.Format = "email"
+		case "url":
+			schema.Format = "uri"
+		case "uuid":
+			schema.Format = "uuid"
+		case "regexp":
+			schema.Pattern = param
+		case "oneof":
+			schema.Enum = strings.Fields(param)
+		case "min", "max", "len":
+			applyBound(schema, name, param)
+		}
+	}
+
+	return required
+}
+
+// applyBound sets the minimum/maximum keyword matching the schema type.
+func applyBound(schema *Schema, name, param string) {
+	n, err := strconv.ParseFloat(param, 64)
+	if err != nil {
+		return
+	}
+	i := int(n)
+
+	switch schema.Type {
+	case "integer", "number":
+		if name != "max" {
+			schema.Minimum = &n
+		}
+		if name != "min" {
+			schema.Maximum = &n
+		}
+	case "string":
+		if name != "max" {
+			schema.MinLength = &i
+		}
+		if name != "min" {
+			schema.MaxLength = &i
+		}
+	case "array":
+		if name != "max" {
+			schema.MinItems = &i
+		}
+		if name != "min" {
+			schema.MaxItems = &i
+		}
+	}
+}
+
+// componentName strips characters not allowed in component keys, f.e.
+// brackets of generic type names.
+func componentName(name string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '.', r == '_', r == '-':
+			return r
+		case r == '/':
+			return '.'
+		}
+		return '_'
+	}, name)
+}
@@ -0,0 +1,176 @@
+package docgen
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// jsonToYAML converts a JSON document into YAML, keeping the order of
+// object keys. Strings are written as double quoted scalars, which share
+// their escaping rules with JSON.
+func jsonToYAML(data []byte) ([]byte, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+
+	v, err := decodeOrdered(dec)
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	writeYAML(&buf, v, 0)
+
+	return buf.Bytes(), nil
+}
+
+type yamlObject []yamlMember
+
+type yamlMember struct {
+	key   string
+	value interface{}
+}
+
+// decodeOrdered decodes the next JSON value, objects are returned as
+// yamlObject to preserve their key order.
+func decodeOrdered(dec *json.Decoder) (interface{}, error) {
+	tok, err := dec.Token()
+	if err != nil {
+		return nil, err
+	}
+
+	switch t := tok.(type) {
+	case json.Delim:
+		switch t {
+		case '{':
+			obj := yamlObject{}
+			for dec.More() {
+				key, err := dec.Token()
+				if err != nil {
+					return nil, err
+				}
+
+				value, err := decodeOrdered(dec)
+				if err != nil {
+					return nil, err
+				}
+				obj = append(obj, yamlMember{key.(string), value})
+			}
+			_, err = dec.Token()
+			return obj, err
+
+		case '[':
+			arr := []interface{}{}
+			for dec.More() {
+				value, err := decodeOrdered(dec)
+				if err != nil {
+					return nil, err
+				}
+				arr = append(arr, value)
+			}
+			_, err = dec.Token()
+			return arr, err
+		}
+
+		return nil, fmt.Errorf("docgen: unexpected delimiter '%s'", t)
+	}
+
+	return tok, nil
+}
+
+func writeYAML(buf *bytes.Buffer, v interface{}, indent int) {
+	pad := strings.Repeat("  ", indent)
+
+	switch t := v.(type) {
+	case yamlObject:
+		for _, m := range t {
+			buf.WriteString(pad + yamlKey(m.key) + ":")
+			writeYAMLValue(buf, m.value, indent)
+		}
+
+	case []interface{}:
+		for _, e := range t {
+			buf.WriteString(pad + "-")
+			if obj, ok := e.(yamlObject); ok && len(obj) > 0 {
+				// first member on the same line as the dash
+				var sub bytes.Buffer
+				writeYAML(&sub, obj, indent+1)
+				buf.WriteString(" " + strings.TrimPrefix(sub.String(), pad+"  "))
+				continue
+			}
+			writeYAMLValue(buf, e, indent)
+		}
+
+	default:
+		buf.WriteString(yamlScalar(v) + "\n")
+	}
+}
+
+// writeYAMLValue writes the value following a key or a dash.
+func writeYAMLValue(buf *bytes.Buffer, v interface{}, indent int) {
+	switch t := v.(type) {
+	case yamlObject:
+		if len(t) == 0 {
+			buf.WriteString(" {}\n")
+			return
+		}
+		buf.WriteString("\n")
+		writeYAML(buf, t, indent+1)
+
+	case []interface{}:
+		if len(t) == 0 {
+			buf.WriteString(" []\n")
+			return
+		}
+		buf.WriteString("\n")
+		writeYAML(buf, t, indent+1)
+
+	default:
+		buf.WriteString(" " + yamlScalar(v) + "\n")
+	}
+}
+
+func yamlScalar(v interface{}) string {
+	switch t := v.(type) {
+	case nil:
+		return "null"
+	case bool:
+		if t {
+			return "true"
+		}
+		return "false"
+	case json.Number:
+		return t.String()
+	case string:
+		quoted, _ := json.Marshal(t)
+		return string(quoted)
+	}
+
+	return fmt.Sprint(v)
+}
+
+// yamlKey quotes keys which would not be read back as plain strings.
+func yamlKey(key string) string {
+	plain := key != ""
+	for i, c := range key {
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c == '_':
+		case c == '$' && i == 0:
+		case (c >= '0' && c <= '9' || c == '.' || c == '-') && i > 0:
+		default:
+			plain = false
+		}
+	}
+
+	switch strings.ToLower(key) {
+	case "true", "false", "yes", "no", "on", "off", "null", "y", "n":
+		plain = false
+	}
+
+	if plain {
+		return key
+	}
+
+	return yamlScalar(key)
+}
@@ -0,0 +1,207 @@
+package phi
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+)
+
+// Envelope wraps the payload written by Response.JSON. meta and links hold
+// the values set via Response.SetMeta, Response.SetLink and
+// Response.Paginate, meta additionally holds the request id of routers with
+// RequestIDMeta. Both are nil if empty.
+//
+// Envelopes are set per router via Mux.Envelope or per route via
+// WithEnvelope, DataEnvelope is used by default.
+type Envelope func(data interface{}, meta, links Map) interface{}
+
+// NoEnvelope writes payloads as they are, f.e. to respond with bare arrays.
+// Meta data and links are dropped.
+func NoEnvelope(data interface{}, meta, links Map) interface{} {
+	return data
+}
+
+// DataEnvelope is the default envelope, content will be wrapped like
+//
+//	{ "data": <content>, "meta": { ... } }
+//
+// meta is omitted if empty, links are dropped.
+func DataEnvelope(data interface{}, meta, links Map) interface{} {
+	body := Map{"data": data}
+	if len(meta) > 0 {
+		body["meta"] = meta
+	}
+
+	return body
+}
+
+// JSONAPIEnvelope wraps content like a JSON:API top-level document
+//
+//	{ "data": <content>, "meta": { ... }, "links": { "self": ..., "next": ... } }
+//
+// meta and links are omitted if empty.
+func JSONAPIEnvelope(data interface{}, meta, links Map) interface{} {
+	body := DataEnvelope(data, meta, links).(Map)
+	if len(links) > 0 {
+		body["links"] = links
+	}
+
+	return body
+}
+
+// WithEnvelope is a middleware setting the envelope of the routes it is used
+// for, f.e.:
+//
+//	r.With(phi.WithEnvelope(phi.NoEnvelope)).Get("/items", listItems)
+func WithEnvelope(e Envelope) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if rctx := RouteContext(r.Context()); rctx != nil {
+				rctx.envelope = e
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// RequestIDMeta adds the id of the request returned by fn to the meta data
+// of envelopes as "requestId", for the router and its subrouters:
+//
+//	r.Use(middleware.RequestID)
+//	r.RequestIDMeta(middleware.GetReqID)
+//
+// Envelopes without meta data don't carry a request id otherwise.
+func (mx *Mux) RequestIDMeta(fn func(ctx context.Context) string) {
+	m := mx
+	if mx.inline && mx.parent != nil {
+		m = mx.parent
+	}
+
+	// Update the request id func from this point forward
+	m.requestIDFunc = fn
+	m.updateSubRoutes(func(subMux *Mux) {
+		if subMux.requestIDFunc == nil {
+			subMux.RequestIDMeta(fn)
+		}
+	})
+}
+
+// Pagination is the meta data written by Response.Paginate
+type Pagination struct {
+	Page       int `json:"page"`
+	PerPage    int `json:"perPage"`
+	Total      int `json:"total"`
+	TotalPages int `json:"totalPages"`
+}
+
+// SetMeta sets a value of the meta data written into the envelope.
+func (w Response) SetMeta(key string, value interface{}) {
+	if rctx := w.routeContext(); rctx != nil {
+		if rctx.meta == nil {
+			rctx.meta = Map{}
+		}
+		rctx.meta[key] = value
+	}
+}
+
+// SetLink sets a link written into the envelope, f.e. "self".
+func (w Response) SetLink(rel, href string) {
+	if rctx := w.routeContext(); rctx != nil {
+		if rctx.links == nil {
+			rctx.links = Map{}
+		}
+		rctx.links[rel] = href
+	}
+}
+
+// Paginate adds the pagination meta data of the response and links to the
+// self, first, prev, next and last page. Pages start at 1 and are linked
+// via the `page` query parameter of the request URL.
+func (w Response) Paginate(page, perPage, total int) {
+	pages := 0
+	if perPage > 0 {
+		pages = (total + perPage - 1) / perPage
+	}
+
+	w.SetMeta("pagination", Pagination{
+		Page:       page,
+		PerPage:    perPage,
+		Total:      total,
+		TotalPages: pages,
+	})
+
+	if w.request == nil {
+		return
+	}
+
+	link := func(p int) string {
+		u := *w.request.URL
+		q := u.Query()
+		q.Set("page", strconv.Itoa(p))
+		u.RawQuery = q.Encode()
+
+		return u.RequestURI()
+	}
+
+	w.SetLink("self", link(page))
+	if pages == 0 {
+		return
+	}
+
+	w.SetLink("first", link(1))
+	w.SetLink("last", link(pages))
+	if page > 1 {
+		w.SetLink("prev", link(page-1))
+	}
+	if page < pages {
+		w.SetLink("next", link(page+1))
+	}
+}
+
+// envelope wraps the data with the envelope of the route
+func (w Response) envelope(data interface{}) interface{} {
+	if w.request == nil {
+		return DataEnvelope(data, nil, nil)
+	}
+
+	e := Envelope(DataEnvelope)
+	var meta, links Map
+	var requestID func(ctx context.Context) string
+
+	if rctx := w.routeContext(); rctx != nil {
+		requestID = rctx.requestIDFunc
+		if rctx.envelope != nil {
+			e = rctx.envelope
+		}
+		links = rctx.links
+
+		if len(rctx.meta) > 0 {
+			meta = make(Map, len(rctx.meta)+1)
+			for k, v := range rctx.meta {
+				meta[k] = v
+			}
+		}
+	}
+
+	if requestID != nil {
+		if id := requestID(w.request.Context()); id != "" {
+			if meta == nil {
+				meta = Map{}
+			}
+			meta["requestId"] = id
+		}
+	}
+
+	return e(data, meta, links)
+}
+
+// routeContext returns the routing context of the request, nil if the
+// Response was created outside of a router
+func (w Response) routeContext() *Context {
+	if w.request == nil {
+		return nil
+	}
+
+	return RouteContext(w.request.Context())
+}
@@ -0,0 +1,138 @@
+package phi
+
+import (
+	"context"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestEnvelope(t *testing.T) {
+	list := func(w *Response, r *Request) *Error {
+		return w.JSON([]string{"a", "b"})
+	}
+
+	r := NewRouter()
+	r.GET("/data", list)
+	r.With(WithEnvelope(NoEnvelope)).GET("/bare", list)
+
+	r.Route("/custom", func(r Router) {
+		r.GET("/", list)
+	})
+	r.Route("/api", func(r Router) {
+		r.Envelope(JSONAPIEnvelope)
+		r.GET("/items", func(w *Response, r *Request) *Error {
+			w.SetMeta("version", 2)
+			w.Paginate(2, 2, 5)
+			return w.JSON([]string{"c", "d"})
+		})
+	})
+
+	sub := NewRouter()
+	sub.GET("/", list)
+	r.Mount("/mounted", sub)
+
+	r.Envelope(func(data interface{}, meta, links Map) interface{} {
+		return Map{"result": data}
+	})
+
+	tests := []struct {
+		path string
+		body string
+	}{
+		{"/data", `{"result":["a","b"]}`},
+		{"/bare", `["a","b"]`},
+		{"/custom/", `{"result":["a","b"]}`},
+		{"/mounted/", `{"result":["a","b"]}`},
+		{"/api/items?page=2&sort=name", `{"data":["c","d"],` +
+			`"links":{"first":"/api/items?page=1\u0026sort=name","last":"/api/items?page=3\u0026sort=name","next":"/api/items?page=3\u0026sort=name","prev":"/api/items?page=1\u0026sort=name","self":"/api/items?page=2\u0026sort=name"},` +
+			`"meta":{"pagination":{"page":2,"perPage":2,"total":5,"totalPages":3},"version":2}}`},
+	}
+
+	for _, tt := range tests {
+		if _, body := testHandler(t, r, "GET", tt.path, nil); body != tt.body {
+			t.Errorf("%s: unexpected body %s", tt.path, body)
+		}
+	}
+}
+
+func TestEnvelopeRequestID(t *testing.T) {
+	handler := func(w *Response, r *Request) *Error {
+		return w.JSON("phi")
+	}
+
+	r := NewRouter()
+	r.GET("/", handler)
+	r.Route("/api", func(r Router) {
+		r.GET("/", handler)
+	})
+	r.RequestIDMeta(func(ctx context.Context) string { return "req-1" })
+
+	plain := NewRouter()
+	plain.GET("/", handler)
+
+	tests := []struct {
+		mux  *Mux
+		path string
+		body string
+	}{
+		{r, "/", `{"data":"phi","meta":{"requestId":"req-1"}}`},
+		{r, "/api/", `{"data":"phi","meta":{"requestId":"req-1"}}`},
+		{plain, "/", `{"data":"phi"}`},
+	}
+
+	for _, tt := range tests {
+		if _, body := testHandler(t, tt.mux, "GET", tt.path, nil); body != tt.body {
+			t.Errorf("%s: unexpected body %s", tt.path, body)
+		}
+	}
+
+	// responses created outside of handlers keep the default envelope
+	w := httptest.NewRecorder()
+	if (Response{ResponseWriter: w}).JSON("phi"); w.Body.String() != `{"data":"phi"}` {
+		t.Errorf("unexpected body %s", w.Body.String())
+	}
+}
+
+func TestEnvelopeMounted(t *testing.T) {
+	list := func(w *Response, r *Request) *Error {
+		return w.JSON([]string{"a"})
+	}
+
+	r := NewRouter()
+	r.With(WithEnvelope(NoEnvelope)).Route("/v1", func(r Router) {
+		r.GET("/", list)
+	})
+
+	sub := NewRouter()
+	sub.GET("/", list)
+	r.With(WithEnvelope(NoEnvelope)).Mount("/v2", sub)
+
+	own := NewRouter()
+	own.Envelope(JSONAPIEnvelope)
+	own.GET("/", list)
+	r.With(WithEnvelope(NoEnvelope)).Mount("/v3", own)
+
+	// subrouters inheriting the envelope keep the one of the route
+	inheriting := NewRouter()
+	inheriting.GET("/", list)
+	r.With(WithEnvelope(NoEnvelope)).Mount("/v4", inheriting)
+	r.Envelope(func(data interface{}, meta, links Map) interface{} {
+		return Map{"result": data}
+	})
+
+	tests := []struct {
+		path string
+		body string
+	}{
+		{"/v1/", `["a"]`},
+		{"/v2/", `["a"]`},
+		{"/v3/", `{"data":["a"]}`},
+		{"/v4/", `["a"]`},
+	}
+
+	for _, tt := range tests {
+		if _, body := testHandler(t, r, "GET", tt.path, nil); body != tt.body {
+			t.Errorf("%s: unexpected body %s", tt.path, body)
+		}
+	}
+}
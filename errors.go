@@ -1,5 +1,7 @@
 package phi
 
+import "fmt"
+
 var (
 	writingError = Error{
 		Error:   "writingError",
@@ -21,6 +23,43 @@ type Error struct {
 	Error      string
 	Message    string
 	StatusCode int
+
+	// Details optionally lists the individual fields which caused the error,
+	// f.e. every violated validation rule of a request body
+	Details []FieldError
+
+	// Type, Title and Instance are the optional RFC 7807 members used by
+	// ProblemErrorHandler, defaulting to "about:blank", the status text of
+	// StatusCode and no instance
+	Type     string
+	Title    string
+	Instance string
+
+	// Extensions are additional members written by ProblemErrorHandler
+	Extensions map[string]interface{}
+}
+
+// FieldError describes a single invalid field of a request
+type FieldError struct {
+	// Path of the field, like items[2].name
+	Path string `json:"path"`
+
+	// Rule which was violated, f.e. required, min or email
+	Rule string `json:"rule"`
+
+	// Human readable description of the violation
+	Message string `json:"message"`
+
+	// Rejected value of the field, if any
+	Value interface{} `json:"value,omitempty"`
+}
+
+// WithDetails returns a copy of the error carrying the given field details
+func (e *Error) WithDetails(details ...FieldError) *Error {
+	err := *e
+	err.Details = append(append([]FieldError{}, e.Details...), details...)
+
+	return &err
 }
 
 // Validation error can be used for validating post bodies
@@ -50,6 +89,15 @@ func QueryParameterError(e string) *Error {
 	}
 }
 
+// Header parameter error for missing or invalid request headers, see BindHeader
+func HeaderParameterError(e string) *Error {
+	return &Error{
+		Error:      "invalidHeaders",
+		Message:    e,
+		StatusCode: 400,
+	}
+}
+
 // Body Parameter error for error handling regarding parameter missing POST body = { "data": "123"} -> data = body parameter
 func BodyParameterError(e string) *Error {
 	return &Error{
@@ -59,6 +107,45 @@ func BodyParameterError(e string) *Error {
 	}
 }
 
+// Invalid body parameter error for body parameters violating their validation rules
+func InvalidBodyParameterError(e string) *Error {
+	return &Error{
+		Error:      "invalidBodyParameters",
+		Message:    e,
+		StatusCode: 400,
+	}
+}
+
+// Request too large error + statuscode 413 for request bodies exceeding
+// their size limit, see BindMultipart
+func RequestTooLargeError(e string) *Error {
+	return &Error{
+		Error:      "requestTooLarge",
+		Message:    e,
+		StatusCode: 413,
+	}
+}
+
+// Not acceptable error + statuscode 406 for requests accepting none of the
+// registered codecs, see Response.Render
+func NotAcceptableError(accept string) *Error {
+	return &Error{
+		Error:      "notAcceptable",
+		Message:    fmt.Sprintf("no representation available for '%s'", accept),
+		StatusCode: 406,
+	}
+}
+
+// Unsupported media type error + statuscode 415 for request bodies without
+// registered codec, see Bind
+func UnsupportedMediaTypeError(contentType string) *Error {
+	return &Error{
+		Error:      "unsupportedMediaType",
+		Message:    fmt.Sprintf("unsupported content type '%s'", contentType),
+		StatusCode: 415,
+	}
+}
+
 // Unknown error for generic error handling
 func UnknownError(e error) *Error {
 	return &Error{
@@ -76,3 +163,13 @@ func Unauthorized() *Error {
 		StatusCode: 401,
 	}
 }
+
+// Forbidden error + statuscode 403, the request is authenticated but lacks
+// the permission
+func Forbidden() *Error {
+	return &Error{
+		Error:      "forbidden",
+		Message:    "insufficient permissions",
+		StatusCode: 403,
+	}
+}
@@ -5,12 +5,14 @@ import (
 	"errors"
 	"log"
 	"net/http"
+	"reflect"
 )
 
 // Defines a function which accepts a ResponseWriter, Request and a phi.Error
 // this function is used to handle errors thrown by any handler/route
 //
-// can be set by SetErrorHandler
+// can be set by SetErrorHandler, routers can define their own via
+// Mux.ErrorHandler
 var ErrorHandler = defaultErrorHandler
 
 func defaultErrorHandler(w http.ResponseWriter, r *http.Request, e *Error) {
@@ -18,16 +20,82 @@ func defaultErrorHandler(w http.ResponseWriter, r *http.Request, e *Error) {
 		w.Header().Set("Content-Type", "application/json")
 	}
 
-	parsed, err := json.Marshal(Map{
+	body := Map{
 		"error":   e.Error,
 		"message": e.Message,
-	})
-	if err == nil {
-		if _, err = w.Write(parsed); err != nil {
-			log.Printf("#> defaultHandler: %v", err)
-		}
+	}
+	if len(e.Details) > 0 {
+		body["errors"] = e.Details
+	}
+
+	writeError(w, e, body)
+}
+
+// ProblemErrorHandler writes errors as RFC 7807 problem details with the
+// content type application/problem+json. Field details of the error are
+// listed in the "errors" extension member:
+//
+//	{
+//	  "type": "about:blank",
+//	  "title": "Bad Request",
+//	  "status": 400,
+//	  "detail": "missing 'name'",
+//	  "error": "missingBodyParameters",
+//	  "errors": [{ "path": "name", "rule": "required", "message": "is required" }]
+//	}
+//
+// The members type, title and instance can be set on the Error, as well as
+// additional extension members.
+//
+// Use Mux.ProblemDetails to enable it for a router.
+func ProblemErrorHandler(w http.ResponseWriter, r *http.Request, e *Error) {
+	w.Header().Set("Content-Type", "application/problem+json")
+
+	status := e.StatusCode
+	if status == 0 {
+		status = http.StatusInternalServerError
+	}
+
+	body := Map{}
+	for k, v := range e.Extensions {
+		body[k] = v
+	}
+
+	body["type"] = "about:blank"
+	if e.Type != "" {
+		body["type"] = e.Type
+	}
+
+	body["title"] = http.StatusText(status)
+	if e.Title != "" {
+		body["title"] = e.Title
+	}
+
+	if e.Instance != "" {
+		body["instance"] = e.Instance
+	}
+
+	body["status"] = status
+	body["detail"] = e.Message
+	body["error"] = e.Error
+
+	if len(e.Details) > 0 {
+		body["errors"] = e.Details
+	}
+
+	writeError(w, e, body)
+}
+
+// writeError writes the status code of the error and the json encoded body
+func writeError(w http.ResponseWriter, e *Error, body Map) {
+	parsed, err := json.Marshal(body)
+	if err != nil {
+		log.Printf("#> errorHandler: %v", err)
 
-		return
+		parsed, _ = json.Marshal(Map{
+			"error":   parseError.Error,
+			"message": parseError.Message,
+		})
 	}
 
 	if e.StatusCode != 0 {
@@ -35,7 +103,7 @@ func defaultErrorHandler(w http.ResponseWriter, r *http.Request, e *Error) {
 	}
 
 	if _, err = w.Write(parsed); err != nil {
-		log.Printf("#> defaultHandler: %v", err)
+		log.Printf("#> errorHandler: %v", err)
 	}
 }
 
@@ -45,18 +113,50 @@ func (h Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	if err := h(
 		&Response{
 			ResponseWriter: w,
+			request:        r,
 		},
 		&Request{
 			Request: r,
 		},
 	); err != nil {
-		ErrorHandler(w, r, err)
+		HandleError(w, r, err)
 	}
 }
 
+// HandleError writes the error with the error handler responsible for the
+// request. That is the handler set via Mux.ErrorHandler or Mux.ProblemDetails
+// of the nearest router and the package level ErrorHandler otherwise.
+//
+// Middlewares should use it to respond with errors, f.e.:
+//
+//	phi.HandleError(w, r, phi.Unauthorized())
+func HandleError(w http.ResponseWriter, r *http.Request, e *Error) {
+	if r != nil {
+		if rctx := RouteContext(r.Context()); rctx != nil && rctx.errorHandler != nil {
+			rctx.errorHandler(w, r, e)
+			return
+		}
+	}
+
+	ErrorHandler(w, r, e)
+}
+
+// useProblemDetails reports whether the request is routed by a Mux with
+// ProblemDetails enabled
+func useProblemDetails(r *http.Request) bool {
+	if r == nil {
+		return false
+	}
+
+	rctx := RouteContext(r.Context())
+	return rctx != nil && rctx.problemDetails
+}
+
 // set custom error handler
 type ErrorHandlerFunc func(w http.ResponseWriter, r *http.Request, e *Error)
 
+// SetErrorHandler replaces the package level ErrorHandler used by all routers
+// without their own, prefer Mux.ErrorHandler to scope it to a router.
 func SetErrorHandler(fn ErrorHandlerFunc) error {
 	if fn == nil {
 		return errors.New("couldn't set empty error handling function")
@@ -65,3 +165,60 @@ func SetErrorHandler(fn ErrorHandlerFunc) error {
 	ErrorHandler = fn
 	return nil
 }
+
+// TypedHandler is implemented by handlers that know the Go types of the
+// request body they decode and the payload they respond with. Tools like
+// the docgen subpackage use it to describe routes.
+//
+// A nil reflect.Type means the type is unknown.
+type TypedHandler interface {
+	http.Handler
+	RequestType() reflect.Type
+	ResponseType() reflect.Type
+}
+
+type typedHandler struct {
+	handler http.Handler
+	in      reflect.Type
+	out     reflect.Type
+}
+
+func (h *typedHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h.handler.ServeHTTP(w, r)
+}
+
+func (h *typedHandler) RequestType() reflect.Type {
+	return h.in
+}
+
+func (h *typedHandler) ResponseType() reflect.Type {
+	return h.out
+}
+
+// Validated returns a handler which decodes and validates the request body
+// into T with Validate before calling fn. The type T is recorded on the
+// handler, see TypedHandler.
+//
+// Example:
+//
+//	r.Method("POST", "/users", phi.Validated(func(w *phi.Response, r *phi.Request, body *User) *phi.Error {
+//		return w.JSON(body)
+//	}))
+func Validated[T any](fn func(w *Response, r *Request, body *T) *Error) http.Handler {
+	return &typedHandler{
+		handler: Handler(func(w *Response, r *Request) *Error {
+			body, err := Validate[T](r)
+			if err != nil {
+				return err
+			}
+
+			return fn(w, r, body)
+		}),
+		in: typeOf[T](),
+	}
+}
+
+// typeOf returns the reflect.Type of T, also for interface types.
+func typeOf[T any]() reflect.Type {
+	return reflect.TypeOf((*T)(nil)).Elem()
+}
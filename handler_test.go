@@ -0,0 +1,234 @@
+package phi
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+type detailsBody struct {
+	Name  string   `json:"name,required"`
+	Age   int      `json:"age" validate:"min=18"`
+	Items []string `json:"items" validate:"dive,len=2"`
+}
+
+func detailsHandler(w *Response, r *Request) *Error {
+	body, err := Validate[detailsBody](r)
+	if err != nil {
+		return err
+	}
+
+	return w.JSON(body)
+}
+
+func TestErrorDetails(t *testing.T) {
+	r := NewRouter()
+	r.POST("/", detailsHandler)
+
+	ts := httptest.NewServer(r)
+	defer ts.Close()
+
+	resp, body := testRequest(t, ts, "POST", "/", bytes.NewBufferString(`{"age":17,"items":["ab","abc"]}`))
+	if resp.StatusCode != 400 {
+		t.Errorf("expected status 400 got %d", resp.StatusCode)
+	}
+	if ct := resp.Header.Get("Content-Type"); ct != "application/json" {
+		t.Errorf("expected content type application/json got %s", ct)
+	}
+
+	expected := `{"error":"invalidBodyParameters","errors":[` +
+		`{"path":"name","rule":"required","message":"is required"},` +
+		`{"path":"age","rule":"min","message":"must be at least 18","value":17},` +
+		`{"path":"items[1]","rule":"len","message":"length must be 2","value":"abc"}],` +
+		`"message":"missing 'name'; invalid 'age' (must be at least 18), 'items[1]' (length must be 2)"}`
+	if body != expected {
+		t.Errorf("expected body %s got %s", expected, body)
+	}
+}
+
+func TestProblemErrorHandler(t *testing.T) {
+	w := httptest.NewRecorder()
+	ProblemErrorHandler(w, httptest.NewRequest("POST", "/", nil), BodyParameterError("missing 'name'").WithDetails(FieldError{
+		Path:    "name",
+		Rule:    "required",
+		Message: "is required",
+	}))
+
+	if w.Code != 400 {
+		t.Errorf("expected status 400 got %d", w.Code)
+	}
+	if ct := w.Header().Get("Content-Type"); ct != "application/problem+json" {
+		t.Errorf("expected content type application/problem+json got %s", ct)
+	}
+
+	expected := `{"detail":"missing 'name'","error":"missingBodyParameters","errors":[{"path":"name","rule":"required","message":"is required"}],"status":400,"title":"Bad Request","type":"about:blank"}`
+	if body := w.Body.String(); body != expected {
+		t.Errorf("expected body %s got %s", expected, body)
+	}
+}
+
+func TestMuxProblemDetails(t *testing.T) {
+	r := NewRouter()
+	r.GET("/legacy", func(w *Response, r *Request) *Error {
+		return URLParameterError("id")
+	})
+
+	r.Route("/api", func(r Router) {
+		r.ProblemDetails()
+
+		r.GET("/error", func(w *Response, r *Request) *Error {
+			return &Error{
+				Error:      "outOfCredit",
+				Message:    "your current balance is 30, but that costs 50",
+				StatusCode: 403,
+				Type:       "https://example.com/probs/out-of-credit",
+				Title:      "You do not have enough credit.",
+				Instance:   "/account/12345/msgs/abc",
+				Extensions: map[string]interface{}{"balance": 30, "status": 200},
+			}
+		})
+
+		r.GET("/response", func(w *Response, r *Request) *Error {
+			return w.ErrorCustomStatus(errors.New("gone"), 410)
+		})
+
+		r.Route("/sub", func(r Router) {
+			r.GET("/", func(w *Response, r *Request) *Error {
+				return w.Error(errors.New("boom"))
+			})
+		})
+	})
+
+	ts := httptest.NewServer(r)
+	defer ts.Close()
+
+	tests := []struct {
+		path   string
+		status int
+		ctype  string
+		body   string
+	}{
+		{"/legacy", 400, "application/json", `{"error":"missingURLParameters","message":"id"}`},
+		{"/api/error", 403, "application/problem+json", `{"balance":30,"detail":"your current balance is 30, but that costs 50","error":"outOfCredit","instance":"/account/12345/msgs/abc","status":403,"title":"You do not have enough credit.","type":"https://example.com/probs/out-of-credit"}`},
+		{"/api/response", 410, "application/problem+json", `{"detail":"gone","error":"unknownError","status":410,"title":"Gone","type":"about:blank"}`},
+		{"/api/sub", 500, "application/problem+json", `{"detail":"boom","error":"unknownError","status":500,"title":"Internal Server Error","type":"about:blank"}`},
+	}
+
+	for _, tt := range tests {
+		resp, body := testRequest(t, ts, "GET", tt.path, nil)
+		if resp.StatusCode != tt.status {
+			t.Errorf("%s: expected status %d got %d", tt.path, tt.status, resp.StatusCode)
+		}
+		if ct := resp.Header.Get("Content-Type"); ct != tt.ctype {
+			t.Errorf("%s: expected content type %s got %s", tt.path, tt.ctype, ct)
+		}
+		if body != tt.body {
+			t.Errorf("%s: expected body %s got %s", tt.path, tt.body, body)
+		}
+	}
+}
+
+func TestMuxErrorHandler(t *testing.T) {
+	textErrors := func(prefix string) ErrorHandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request, e *Error) {
+			w.WriteHeader(e.StatusCode)
+			w.Write([]byte(prefix + ":" + e.Error))
+		}
+	}
+	fail := func(w *Response, r *Request) *Error {
+		return Unauthorized()
+	}
+
+	public := NewRouter()
+	public.GET("/", fail)
+
+	admin := NewRouter()
+	admin.ErrorHandler(textErrors("admin"))
+	admin.GET("/", fail)
+
+	admin.Route("/route", func(r Router) {
+		r.GET("/", fail)
+	})
+	admin.Group(func(r Router) {
+		r.GET("/group", fail)
+	})
+	admin.Route("/own", func(r Router) {
+		r.ErrorHandler(textErrors("own"))
+		r.GET("/", fail)
+	})
+	admin.Route("/resolve", func(r Router) {
+		r.Resolve("user", func(w *Response, r *Request) (any, *Error) {
+			return nil, Unauthorized()
+		})
+		r.GET("/", fail)
+	})
+
+	// mounted before the parent defines an error handler
+	late := NewRouter()
+	late.GET("/", fail)
+	parent := NewRouter()
+	parent.Mount("/late", late)
+	parent.ErrorHandler(textErrors("parent"))
+
+	// mounted after the parent defines an error handler
+	mounted := NewRouter()
+	mounted.GET("/", fail)
+	admin.Mount("/mounted", mounted)
+
+	tests := []struct {
+		mux  *Mux
+		path string
+		body string
+	}{
+		{public, "/", `{"error":"unauthorized","message":"invalid token"}`},
+		{admin, "/", "admin:unauthorized"},
+		{admin, "/route", "admin:unauthorized"},
+		{admin, "/group", "admin:unauthorized"},
+		{admin, "/own", "own:unauthorized"},
+		{admin, "/resolve", "admin:unauthorized"},
+		{admin, "/mounted", "admin:unauthorized"},
+		{parent, "/late", "parent:unauthorized"},
+	}
+
+	for _, tt := range tests {
+		resp, body := testHandler(t, tt.mux, "GET", tt.path, nil)
+		if resp.StatusCode != 401 {
+			t.Errorf("%s: expected status 401 got %d", tt.path, resp.StatusCode)
+		}
+		if body != tt.body {
+			t.Errorf("%s: expected body %s got %s", tt.path, tt.body, body)
+		}
+	}
+}
+
+func TestResponseErrorHandler(t *testing.T) {
+	r := NewRouter()
+	r.ErrorHandler(func(w http.ResponseWriter, r *http.Request, e *Error) {
+		w.WriteHeader(e.StatusCode)
+		w.Write([]byte("custom:" + e.Message))
+	})
+	r.GET("/error", func(w *Response, r *Request) *Error {
+		return w.Error(errors.New("boom"))
+	})
+	r.GET("/status", func(w *Response, r *Request) *Error {
+		return w.ErrorCustomStatus(errors.New("gone"), 410)
+	})
+
+	tests := []struct {
+		path   string
+		status int
+		body   string
+	}{
+		{"/error", 500, "custom:boom"},
+		{"/status", 410, "custom:gone"},
+	}
+
+	for _, tt := range tests {
+		resp, body := testHandler(t, r, "GET", tt.path, nil)
+		if resp.StatusCode != tt.status || body != tt.body {
+			t.Errorf("%s: expected %d %s got %d %s", tt.path, tt.status, tt.body, resp.StatusCode, body)
+		}
+	}
+}
@@ -0,0 +1,150 @@
+package phi
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"regexp"
+	"strings"
+)
+
+// hostRoute is a subrouter serving the requests of hosts matching pattern
+type hostRoute struct {
+	pattern string
+	rex     *regexp.Regexp
+	keys    []string
+	mux     *Mux
+}
+
+// Host creates a new Mux with a fresh middleware stack serving the requests
+// whose host matches `pattern`. Host patterns use the same placeholder and
+// regexp syntax as routing patterns, the captured values are available via
+// URLParam:
+//
+//	r.Host("{tenant}.api.example.com", func(r phi.Router) {
+//		r.Get("/users", listUsers) // phi.URLParam(r, "tenant")
+//	})
+//
+// Hosts are matched case-insensitively and without port in the order they
+// were registered, requests not matching any host are routed by the routes
+// of the mux itself.
+func (mx *Mux) Host(pattern string, fn func(r Router)) Router {
+	if fn == nil {
+		panic(fmt.Sprintf("phi: attempting to Host() a nil subrouter on '%s'", pattern))
+	}
+
+	rex, keys, err := compileHost(pattern)
+	if err != nil {
+		panic(fmt.Sprintf("phi: invalid host pattern '%s': %v", pattern, err))
+	}
+
+	subRouter := NewRouter(mx.opts...)
+	if mx.inline {
+		subRouter.Use(mx.middlewares...)
+	}
+	fn(subRouter)
+
+	m := mx
+	for m.inline && m.parent != nil {
+		m = m.parent
+	}
+
+	for _, h := range m.hosts {
+		if h.pattern == pattern {
+			panic(fmt.Sprintf("phi: attempting to Host() a subrouter on an existing host, '%s'", pattern))
+		}
+	}
+
+	// Build the computed routing handler, as with routes the middleware stack
+	// is complete from here on.
+	if !m.inline && m.handler == nil {
+		m.updateRouteHandler()
+	}
+
+	m.inherit(subRouter)
+	m.hosts = append(m.hosts, &hostRoute{pattern: pattern, rex: rex, keys: keys, mux: subRouter})
+
+	return subRouter
+}
+
+// findHost returns the subrouter of the first host matching `host` and adds
+// its params to the routing context
+func (mx *Mux) findHost(rctx *Context, host string) *Mux {
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+
+	for _, h := range mx.hosts {
+		values := h.rex.FindStringSubmatch(host)
+		if values == nil {
+			continue
+		}
+
+		for i, key := range h.keys {
+			rctx.URLParams.Add(key, values[i+1])
+		}
+		return h.mux
+	}
+
+	return nil
+}
+
+// compileHost turns a host pattern into a regexp capturing the values of its
+// params in order
+func compileHost(pattern string) (*regexp.Regexp, []string, error) {
+	var (
+		b    strings.Builder
+		keys []string
+	)
+	b.WriteString("(?i)^")
+
+	for pattern != "" {
+		typ, key, rexpat, _, ps, pe := patNextSegment(pattern)
+		if typ == ntStatic {
+			b.WriteString(regexp.QuoteMeta(pattern))
+			break
+		}
+		b.WriteString(regexp.QuoteMeta(pattern[:ps]))
+		pattern = pattern[pe:]
+
+		switch typ {
+		case ntParam:
+			b.WriteString("([^.]+)")
+		case ntRegexp:
+			rexpat = strings.TrimSuffix(strings.TrimPrefix(rexpat, "^"), "$")
+			if _, err := regexp.Compile(rexpat); err != nil {
+				return nil, nil, err
+			}
+			b.WriteString("(" + rexpat + ")")
+		case ntCatchAll:
+			b.WriteString("(.*)")
+		}
+		keys = append(keys, key)
+	}
+	b.WriteString("$")
+
+	rex, err := regexp.Compile(b.String())
+	if err != nil {
+		return nil, nil, err
+	}
+	if rex.NumSubexp() != len(keys) {
+		return nil, nil, fmt.Errorf("capturing groups are not supported in host params")
+	}
+
+	return rex, keys, nil
+}
+
+// hostRoutes describes the host subrouters as routes, see Route.Host
+func (mx *Mux) hostRoutes() []Route {
+	rts := make([]Route, 0, len(mx.hosts))
+	for _, h := range mx.hosts {
+		rts = append(rts, Route{
+			SubRoutes: h.mux,
+			Handlers:  map[string]http.Handler{"*": h.mux},
+			Pattern:   "/*",
+			Host:      h.pattern,
+		})
+	}
+
+	return rts
+}
@@ -0,0 +1,118 @@
+package phi
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func TestHost(t *testing.T) {
+	r := NewRouter()
+	r.NotFound(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(404)
+		w.Write([]byte("nothing here"))
+	})
+
+	r.Host("{tenant}.api.example.com", func(r Router) {
+		r.Get("/users/{id}", func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte("users of " + URLParam(r, "tenant") + ": " + URLParam(r, "id")))
+		})
+	})
+	r.With(func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("X-Region", "eu")
+			next.ServeHTTP(w, r)
+		})
+	}).Host("{region:(?:eu|us)}-{n:\\d+}.example.com", func(r Router) {
+		r.Get("/", func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte("region " + URLParam(r, "region") + URLParam(r, "n")))
+		})
+	})
+	r.Get("/", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("default"))
+	})
+
+	tests := []struct {
+		host   string
+		path   string
+		status int
+		body   string
+	}{
+		{"acme.api.example.com", "/users/1", 200, "users of acme: 1"},
+		{"ACME.api.example.com:8080", "/users/2", 200, "users of ACME: 2"},
+		{"acme.api.example.com", "/", 404, "nothing here"},
+		{"a.b.api.example.com", "/", 200, "default"},
+		{"eu-1.example.com", "/", 200, "region eu1"},
+		{"ap-1.example.com", "/", 200, "default"},
+		{"example.com", "/", 200, "default"},
+	}
+
+	for _, tt := range tests {
+		req := httptest.NewRequest("GET", tt.path, nil)
+		req.Host = tt.host
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+
+		if w.Code != tt.status || w.Body.String() != tt.body {
+			t.Errorf("%s%s: expected %d %q, got %d %q", tt.host, tt.path, tt.status, tt.body, w.Code, w.Body.String())
+		}
+	}
+
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Host = "us-2.example.com"
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Header().Get("X-Region") != "eu" {
+		t.Error("expected inline middlewares to apply to the host subrouter")
+	}
+}
+
+func TestHostWalk(t *testing.T) {
+	r := NewRouter()
+	r.Get("/", func(w http.ResponseWriter, r *http.Request) {})
+	r.Host("{tenant}.example.com", func(r Router) {
+		r.Get("/users", func(w http.ResponseWriter, r *http.Request) {})
+		r.Route("/orders", func(r Router) {
+			r.Post("/", func(w http.ResponseWriter, r *http.Request) {})
+		})
+	})
+	r.Route("/api", func(r Router) {
+		r.Host("admin.example.com", func(r Router) {
+			r.Get("/stats", func(w http.ResponseWriter, r *http.Request) {})
+		})
+	})
+
+	routes := r.Routes()
+	if host := routes[len(routes)-1]; host.Host != "{tenant}.example.com" || host.Pattern != "/*" || host.SubRoutes == nil {
+		t.Fatalf("unexpected host route %+v", host)
+	}
+
+	var got []string
+	Walk(r, func(method string, route string, handler http.Handler, middlewares ...func(http.Handler) http.Handler) error {
+		got = append(got, method+" "+route)
+		return nil
+	})
+	sort.Strings(got)
+
+	expected := []string{
+		"GET /",
+		"GET admin.example.com/api/stats",
+		"GET {tenant}.example.com/users",
+		"POST {tenant}.example.com/orders/",
+	}
+	if strings.Join(got, ", ") != strings.Join(expected, ", ") {
+		t.Errorf("expected routes %v, got %v", expected, got)
+	}
+}
+
+func TestHostInvalid(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("expected panic for capturing group in host param")
+		}
+	}()
+
+	NewRouter().Host("{sub:(a|b)}.example.com", func(r Router) {})
+}
@@ -0,0 +1,209 @@
+package jwtauth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/lestrrat-go/jwx/v2/jwa"
+	"github.com/lestrrat-go/jwx/v2/jwk"
+	"github.com/lestrrat-go/jwx/v2/jws"
+	"github.com/lestrrat-go/jwx/v2/jwt"
+)
+
+var (
+	ErrNoSigningKey = errors.New("no signing key")
+	ErrKeyNotFound  = errors.New("key not found")
+)
+
+// UnknownKeyRefreshInterval is the minimum interval between refreshes of
+// remote key sets triggered by tokens of unknown key ids.
+var UnknownKeyRefreshInterval = 30 * time.Second
+
+// NewWithKeySet returns a JWTAuth verifying tokens with the key of the set
+// matching their "kid" header, f.e. of an external identity provider or to
+// rotate keys without downtime:
+//
+//	set, err := jwtauth.KeySetFromURL(ctx, "https://idp.example.com/.well-known/jwks.json", time.Hour)
+//	...
+//	tokenAuth, err := jwtauth.NewWithKeySet(set, "")
+//
+// Tokens are encoded with the key `signKid` of the set, which has to carry
+// its "alg". The set may hold several signing keys, see SetSigningKey. An
+// empty `signKid` verifies tokens only.
+//
+// Remote key sets of KeySetFromURL are refreshed when a token of an unknown
+// key id arrives, at most once per UnknownKeyRefreshInterval.
+func NewWithKeySet(set jwk.Set, signKid string, validateOptions ...jwt.ValidateOption) (*JWTAuth, error) {
+	ja := &JWTAuth{
+		keySet:          set,
+		validateOptions: validateOptions,
+	}
+	ja.verifier = jwt.WithKeyProvider(&keySetProvider{ja: ja})
+
+	if signKid != "" {
+		if err := ja.SetSigningKey(signKid); err != nil {
+			return nil, err
+		}
+	}
+
+	return ja, nil
+}
+
+// SetSigningKey makes the key `kid` of the key set the key encoding tokens,
+// tokens signed with other keys of the set are still verified.
+func (ja *JWTAuth) SetSigningKey(kid string) error {
+	if ja.keySet == nil {
+		return fmt.Errorf("jwtauth: signing key '%s' without key set", kid)
+	}
+
+	key, ok := ja.keySet.LookupKeyID(kid)
+	if !ok {
+		return fmt.Errorf("jwtauth: signing key '%s': %w", kid, ErrKeyNotFound)
+	}
+
+	var alg jwa.SignatureAlgorithm
+	if err := alg.Accept(key.Algorithm()); err != nil || alg == "" {
+		return fmt.Errorf("jwtauth: signing key '%s' without signature algorithm", kid)
+	}
+
+	ja.mu.Lock()
+	defer ja.mu.Unlock()
+	ja.alg, ja.signKey = alg, key
+
+	return nil
+}
+
+// KeySet returns the key set of a JWTAuth created by NewWithKeySet.
+func (ja *JWTAuth) KeySet() jwk.Set {
+	return ja.keySet
+}
+
+// KeySetFromFile reads a JWKS file, like:
+//
+//	{"keys": [{"kty": "oct", "kid": "2024-05", "alg": "HS256", "k": "..."}]}
+func KeySetFromFile(path string) (jwk.Set, error) {
+	set, err := jwk.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("jwtauth: reading key set: %w", err)
+	}
+
+	return set, nil
+}
+
+// keySet is embedded by RemoteKeySet, whose field can't be named Set as
+// the Set method of jwk.Set would be shadowed
+type keySet = jwk.Set
+
+// RemoteKeySet is a key set fetched from a JWKS URL and refreshed in the
+// background, see KeySetFromURL.
+type RemoteKeySet struct {
+	keySet
+
+	cache *jwk.Cache
+	url   string
+}
+
+// KeySetFromURL fetches the JWKS at url and refreshes it in the background
+// until ctx is done. The key set is refreshed every `interval`, or as the
+// cache headers of the response advise if zero.
+func KeySetFromURL(ctx context.Context, url string, interval time.Duration) (*RemoteKeySet, error) {
+	cache := jwk.NewCache(ctx)
+
+	var opts []jwk.RegisterOption
+	if interval > 0 {
+		opts = append(opts, jwk.WithRefreshInterval(interval))
+	}
+	if err := cache.Register(url, opts...); err != nil {
+		return nil, fmt.Errorf("jwtauth: registering key set: %w", err)
+	}
+
+	if _, err := cache.Refresh(ctx, url); err != nil {
+		return nil, fmt.Errorf("jwtauth: fetching key set: %w", err)
+	}
+
+	return &RemoteKeySet{keySet: jwk.NewCachedSet(cache, url), cache: cache, url: url}, nil
+}
+
+// Refresh fetches the key set anew.
+func (s *RemoteKeySet) Refresh(ctx context.Context) error {
+	_, err := s.cache.Refresh(ctx, s.url)
+	return err
+}
+
+// keySetProvider provides the key of the key set matching the "kid" of a
+// token to verify it
+type keySetProvider struct {
+	ja *JWTAuth
+
+	mu          sync.Mutex
+	lastRefresh time.Time
+}
+
+func (kp *keySetProvider) FetchKeys(ctx context.Context, sink jws.KeySink, sig *jws.Signature, _ *jws.Message) error {
+	kid := sig.ProtectedHeaders().KeyID()
+	if kid == "" {
+		return fmt.Errorf("jwtauth: token without key id")
+	}
+
+	key, ok := kp.ja.keySet.LookupKeyID(kid)
+	if !ok && kp.refresh(ctx) {
+		key, ok = kp.ja.keySet.LookupKeyID(kid)
+	}
+	if !ok {
+		return fmt.Errorf("jwtauth: key '%s': %w", kid, ErrKeyNotFound)
+	}
+
+	if usage := key.KeyUsage(); usage != "" && usage != jwk.ForSignature.String() {
+		return fmt.Errorf("jwtauth: key '%s' is not a signature key", kid)
+	}
+
+	// the algorithm of the token has to match the key
+	alg := sig.ProtectedHeaders().Algorithm()
+	if keyAlg := key.Algorithm(); keyAlg.String() != "" {
+		if keyAlg.String() != alg.String() {
+			return ErrAlgoInvalid
+		}
+	} else {
+		algs, err := jws.AlgorithmsForKey(key)
+		if err != nil {
+			return err
+		}
+		if !containsAlgorithm(algs, alg) {
+			return ErrAlgoInvalid
+		}
+	}
+
+	sink.Key(alg, key)
+	return nil
+}
+
+// refresh refreshes remote key sets, it reports whether the set was
+// refreshed
+func (kp *keySetProvider) refresh(ctx context.Context) bool {
+	remote, ok := kp.ja.keySet.(*RemoteKeySet)
+	if !ok {
+		return false
+	}
+
+	kp.mu.Lock()
+	defer kp.mu.Unlock()
+
+	if !kp.lastRefresh.IsZero() && time.Since(kp.lastRefresh) < UnknownKeyRefreshInterval {
+		return false
+	}
+	kp.lastRefresh = time.Now()
+
+	return remote.Refresh(ctx) == nil
+}
+
+func containsAlgorithm(algs []jwa.SignatureAlgorithm, alg jwa.SignatureAlgorithm) bool {
+	for _, a := range algs {
+		if a == alg {
+			return true
+		}
+	}
+	return false
+}
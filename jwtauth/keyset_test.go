@@ -0,0 +1,173 @@
+package jwtauth_test
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/lestrrat-go/jwx/v2/jwa"
+	"github.com/lestrrat-go/jwx/v2/jwk"
+	"github.com/lestrrat-go/jwx/v2/jws"
+	"github.com/lestrrat-go/jwx/v2/jwt"
+	"go.philip.id/phi"
+	"go.philip.id/phi/jwtauth"
+)
+
+func TestKeySetRotation(t *testing.T) {
+	set := jwk.NewSet()
+	for _, kid := range []string{"2024-05", "2024-06"} {
+		set.AddKey(newKey(t, []byte("secret-"+kid), kid, jwa.HS256))
+	}
+
+	data, _ := json.Marshal(set)
+	path := filepath.Join(t.TempDir(), "jwks.json")
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	set, err := jwtauth.KeySetFromFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tokenAuth, err := jwtauth.NewWithKeySet(set, "2024-05")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, oldToken, err := tokenAuth.Encode(map[string]interface{}{"user_id": "philip"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := tokenAuth.SetSigningKey("2024-06"); err != nil {
+		t.Fatal(err)
+	}
+	_, newToken, _ := tokenAuth.Encode(map[string]interface{}{"user_id": "philip"})
+
+	msg, _ := jws.Parse([]byte(newToken))
+	if kid := msg.Signatures()[0].ProtectedHeaders().KeyID(); kid != "2024-06" {
+		t.Errorf("expected kid 2024-06, got %q", kid)
+	}
+
+	r := phi.NewRouter()
+	r.Use(jwtauth.Verifier(tokenAuth), jwtauth.Authenticator(tokenAuth))
+	r.Get("/", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("welcome"))
+	})
+
+	ts := httptest.NewServer(r)
+	defer ts.Close()
+
+	tests := []struct {
+		name   string
+		token  string
+		status int
+	}{
+		{"previous key", oldToken, 200},
+		{"current key", newToken, 200},
+		{"without kid", newJwtToken([]byte("secret-2024-06")), 401},
+		{"unknown kid", signToken(t, newKey(t, []byte("secret-2024-06"), "2024-07", jwa.HS256)), 401},
+		{"wrong key", signToken(t, newKey(t, []byte("guessed"), "2024-06", jwa.HS256)), 401},
+		{"wrong algorithm", signToken(t, newKey(t, []byte("secret-2024-06"), "2024-06", jwa.HS512)), 401},
+	}
+
+	for _, tt := range tests {
+		h := http.Header{}
+		h.Set("Authorization", "BEARER "+tt.token)
+		if status, resp := testRequest(t, ts, "GET", "/", h, nil); status != tt.status {
+			t.Errorf("%s: expected status %d, got %d: %s", tt.name, tt.status, status, resp)
+		}
+	}
+
+	if err := tokenAuth.SetSigningKey("2023-01"); !errors.Is(err, jwtauth.ErrKeyNotFound) {
+		t.Errorf("expected ErrKeyNotFound, got %v", err)
+	}
+}
+
+func TestKeySetFromURL(t *testing.T) {
+	keys := map[string]*rsa.PrivateKey{}
+	for _, kid := range []string{"a", "b"} {
+		pk, err := rsa.GenerateKey(rand.Reader, 2048)
+		if err != nil {
+			t.Fatal(err)
+		}
+		keys[kid] = pk
+	}
+
+	// the identity provider publishes the public key of "b" once it rotates
+	var mu sync.Mutex
+	published := []string{"a"}
+
+	idp := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		set := jwk.NewSet()
+		for _, kid := range published {
+			key := newKey(t, keys[kid], kid, jwa.RS256)
+			pub, _ := key.PublicKey()
+			set.AddKey(pub)
+		}
+		json.NewEncoder(w).Encode(set)
+	}))
+	defer idp.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	set, err := jwtauth.KeySetFromURL(ctx, idp.URL, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tokenAuth, err := jwtauth.NewWithKeySet(set, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := jwtauth.VerifyToken(tokenAuth, signToken(t, newKey(t, keys["a"], "a", jwa.RS256))); err != nil {
+		t.Errorf("expected token of key a to verify, got %v", err)
+	}
+
+	mu.Lock()
+	published = append(published, "b")
+	mu.Unlock()
+
+	if _, err := jwtauth.VerifyToken(tokenAuth, signToken(t, newKey(t, keys["b"], "b", jwa.RS256))); err != nil {
+		t.Errorf("expected token of rotated key b to verify, got %v", err)
+	}
+
+	if _, _, err := tokenAuth.Encode(map[string]interface{}{}); !errors.Is(err, jwtauth.ErrNoSigningKey) {
+		t.Errorf("expected ErrNoSigningKey, got %v", err)
+	}
+}
+
+func newKey(t *testing.T, raw interface{}, kid string, alg jwa.SignatureAlgorithm) jwk.Key {
+	key, err := jwk.FromRaw(raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	key.Set(jwk.KeyIDKey, kid)
+	key.Set(jwk.AlgorithmKey, alg)
+	return key
+}
+
+func signToken(t *testing.T, key jwk.Key) string {
+	token := jwt.New()
+	token.Set("user_id", "philip")
+
+	payload, err := jwt.Sign(token, jwt.WithKey(key.Algorithm(), key))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(payload)
+}
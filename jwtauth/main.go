@@ -5,16 +5,21 @@ import (
 	"errors"
 	"net/http"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/lestrrat-go/jwx/v2/jwa"
+	"github.com/lestrrat-go/jwx/v2/jwk"
 	"github.com/lestrrat-go/jwx/v2/jwt"
 )
 
 type JWTAuth struct {
+	mu              sync.RWMutex // guards alg, signKey and revocations, see SetSigningKey
 	alg             jwa.SignatureAlgorithm
 	signKey         interface{} // private-key
 	verifyKey       interface{} // public-key, only used by RSA and ECDSA algorithms
+	keySet          jwk.Set     // keys by their id, see NewWithKeySet
+	revocations     RevocationStore
 	verifier        jwt.ParseOption
 	validateOptions []jwt.ValidateOption
 }
@@ -31,6 +36,7 @@ var (
 	ErrIATInvalid   = errors.New("token iat validation failed")
 	ErrNoTokenFound = errors.New("no token found")
 	ErrAlgoInvalid  = errors.New("algorithm mismatch")
+	ErrRevoked      = errors.New("token is revoked")
 )
 
 func New(alg string, signKey interface{}, verifyKey interface{}, validateOptions ...jwt.ValidateOption) *JWTAuth {
@@ -97,10 +103,17 @@ func VerifyRequest(ja *JWTAuth, r *http.Request, findTokenFns ...func(r *http.Re
 		return nil, ErrNoTokenFound
 	}
 
-	return VerifyToken(ja, tokenString)
+	return verifyToken(r.Context(), ja, tokenString)
 }
 
+// VerifyToken decodes and validates an access token. Refresh tokens of an
+// Issuer are rejected, as are tokens revoked in the store of
+// SetRevocationStore.
 func VerifyToken(ja *JWTAuth, tokenString string) (jwt.Token, error) {
+	return verifyToken(context.Background(), ja, tokenString)
+}
+
+func verifyToken(ctx context.Context, ja *JWTAuth, tokenString string) (jwt.Token, error) {
 	// Decode & verify the token
 	token, err := ja.Decode(tokenString)
 	if err != nil {
@@ -115,6 +128,14 @@ func VerifyToken(ja *JWTAuth, tokenString string) (jwt.Token, error) {
 		return token, ErrorReason(err)
 	}
 
+	if use, _ := token.Get(TokenUseClaim); use == "refresh" {
+		return token, ErrUnauthorized
+	}
+
+	if ja.revoked(ctx, token) {
+		return token, ErrRevoked
+	}
+
 	// Valid!
 	return token, nil
 }
@@ -141,7 +162,15 @@ func (ja *JWTAuth) ValidateOptions() []jwt.ValidateOption {
 }
 
 func (ja *JWTAuth) sign(token jwt.Token) ([]byte, error) {
-	return jwt.Sign(token, jwt.WithKey(ja.alg, ja.signKey))
+	ja.mu.RLock()
+	alg, signKey := ja.alg, ja.signKey
+	ja.mu.RUnlock()
+
+	if signKey == nil {
+		return nil, ErrNoSigningKey
+	}
+
+	return jwt.Sign(token, jwt.WithKey(alg, signKey))
 }
 
 func (ja *JWTAuth) parse(payload []byte) (jwt.Token, error) {
@@ -159,6 +188,8 @@ func ErrorReason(err error) error {
 		return ErrIATInvalid
 	case errors.Is(err, jwt.ErrTokenNotYetValid()), err == ErrNBFInvalid:
 		return ErrNBFInvalid
+	case err == ErrRevoked:
+		return ErrRevoked
 	default:
 		return ErrUnauthorized
 	}
@@ -0,0 +1,278 @@
+package jwtauth
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/lestrrat-go/jwx/v2/jwt"
+)
+
+var (
+	ErrTokenReused       = errors.New("refresh token reused")
+	ErrNoRevocationStore = errors.New("no revocation store")
+	ErrNoTokenID         = errors.New("token without jti")
+)
+
+const (
+	// SessionClaim holds the id of the session of issued token pairs, all
+	// tokens of a session are revoked together
+	SessionClaim = "sid"
+
+	// TokenUseClaim is "refresh" for refresh tokens, which VerifyToken
+	// rejects as access tokens
+	TokenUseClaim = "token_use"
+)
+
+// IssuerOptions configure the lifetimes of issued tokens, see NewIssuer.
+type IssuerOptions struct {
+	// AccessTTL is the lifetime of access tokens, 15 minutes by default
+	AccessTTL time.Duration
+
+	// RefreshTTL is the lifetime of refresh tokens, 30 days by default
+	RefreshTTL time.Duration
+}
+
+func (o IssuerOptions) withDefaults() IssuerOptions {
+	if o.AccessTTL <= 0 {
+		o.AccessTTL = 15 * time.Minute
+	}
+	if o.RefreshTTL <= 0 {
+		o.RefreshTTL = 30 * 24 * time.Hour
+	}
+	return o
+}
+
+// TokenPair is a short-lived access token and the refresh token to renew
+// it, encoded like an OAuth 2.0 token response.
+type TokenPair struct {
+	AccessToken  string `json:"access_token"`
+	RefreshToken string `json:"refresh_token"`
+	TokenType    string `json:"token_type"`
+	ExpiresIn    int64  `json:"expires_in"`
+}
+
+// Issuer issues token pairs and rotates refresh tokens: each refresh token
+// is accepted once and replaced by a new one. Presenting a rotated refresh
+// token again revokes its whole session, as either the client or an
+// attacker holds a stolen token.
+type Issuer struct {
+	ja   *JWTAuth
+	opts IssuerOptions
+}
+
+// NewIssuer returns an Issuer signing tokens with ja. A
+// MemoryRevocationStore is set on ja unless it has a store already:
+//
+//	tokenAuth := jwtauth.New("HS256", []byte("secret"), nil)
+//	issuer := jwtauth.NewIssuer(tokenAuth, jwtauth.IssuerOptions{AccessTTL: 5 * time.Minute})
+//
+//	pair, err := issuer.Issue(ctx, map[string]interface{}{"sub": user.ID})
+func NewIssuer(ja *JWTAuth, opts IssuerOptions) *Issuer {
+	ja.mu.Lock()
+	if ja.revocations == nil {
+		ja.revocations = NewMemoryRevocationStore()
+	}
+	ja.mu.Unlock()
+
+	return &Issuer{ja: ja, opts: opts.withDefaults()}
+}
+
+// Issue starts a new session and returns its first token pair, both tokens
+// carry the claims.
+func (is *Issuer) Issue(ctx context.Context, claims map[string]interface{}) (*TokenPair, error) {
+	sid, err := newID()
+	if err != nil {
+		return nil, err
+	}
+
+	return is.issue(claims, sid)
+}
+
+// Refresh verifies and rotates the refresh token, it returns a new token pair
+// of the session with the claims of the refresh token. Reusing a rotated
+// refresh token revokes the session and returns ErrTokenReused.
+func (is *Issuer) Refresh(ctx context.Context, refreshToken string) (*TokenPair, error) {
+	token, err := is.ja.Decode(refreshToken)
+	if err != nil {
+		return nil, ErrorReason(err)
+	}
+	if err := jwt.Validate(token, is.ja.validateOptions...); err != nil {
+		return nil, ErrorReason(err)
+	}
+
+	use, _ := token.Get(TokenUseClaim)
+	sid, _ := token.Get(SessionClaim)
+	session, _ := sid.(string)
+	if use != "refresh" || session == "" || token.JwtID() == "" {
+		return nil, ErrUnauthorized
+	}
+
+	store := is.ja.RevocationStore()
+	revoked, err := store.IsRevoked(ctx, session)
+	if err != nil {
+		return nil, err
+	}
+	if revoked {
+		return nil, ErrRevoked
+	}
+
+	reused, err := store.Revoke(ctx, token.JwtID(), token.Expiration())
+	if err != nil {
+		return nil, err
+	}
+	if reused {
+		if _, err := store.Revoke(ctx, session, time.Now().Add(is.opts.RefreshTTL)); err != nil {
+			return nil, err
+		}
+		return nil, ErrTokenReused
+	}
+
+	claims := token.PrivateClaims()
+	delete(claims, TokenUseClaim)
+	delete(claims, SessionClaim)
+	if sub := token.Subject(); sub != "" {
+		claims[jwt.SubjectKey] = sub
+	}
+	if iss := token.Issuer(); iss != "" {
+		claims[jwt.IssuerKey] = iss
+	}
+	if aud := token.Audience(); len(aud) > 0 {
+		claims[jwt.AudienceKey] = aud
+	}
+
+	return is.issue(claims, session)
+}
+
+// RevokeSession revokes the session of an access or refresh token, f.e. to
+// log out.
+func (is *Issuer) RevokeSession(ctx context.Context, tokenString string) error {
+	token, err := is.ja.Decode(tokenString)
+	if err != nil {
+		return ErrorReason(err)
+	}
+
+	sid, _ := token.Get(SessionClaim)
+	session, _ := sid.(string)
+	if session == "" {
+		return ErrUnauthorized
+	}
+
+	_, err = is.ja.RevocationStore().Revoke(ctx, session, time.Now().Add(is.opts.RefreshTTL))
+	return err
+}
+
+// RefreshHandler returns a handler exchanging a refresh token for a new token
+// pair, like the OAuth 2.0 refresh grant. The refresh token is read from the
+// "refresh_token" field of a json or form body:
+//
+//	r.Method("POST", "/token/refresh", issuer.RefreshHandler())
+//
+// Invalid, expired, revoked and reused refresh tokens are answered with
+// 400 and the error "invalid_grant", failures of the revocation store with
+// 500.
+func (is *Issuer) RefreshHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			RefreshToken string `json:"refresh_token"`
+		}
+
+		if strings.HasPrefix(r.Header.Get("Content-Type"), "application/json") {
+			if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<16)).Decode(&body); err != nil {
+				writeTokenError(w, "invalid_request", "malformed body")
+				return
+			}
+		} else {
+			body.RefreshToken = r.PostFormValue("refresh_token")
+		}
+
+		if body.RefreshToken == "" {
+			writeTokenError(w, "invalid_request", "missing refresh_token")
+			return
+		}
+
+		pair, err := is.Refresh(r.Context(), body.RefreshToken)
+		switch err {
+		case nil:
+		case ErrUnauthorized, ErrExpired, ErrNBFInvalid, ErrIATInvalid, ErrRevoked, ErrTokenReused:
+			writeTokenError(w, "invalid_grant", err.Error())
+			return
+		default:
+			writeTokenJSON(w, http.StatusInternalServerError, map[string]string{"error": "server_error"})
+			return
+		}
+
+		writeTokenJSON(w, http.StatusOK, pair)
+	})
+}
+
+func (is *Issuer) issue(claims map[string]interface{}, session string) (*TokenPair, error) {
+	now := time.Now()
+
+	access, err := is.encode(claims, session, "", now.Add(is.opts.AccessTTL))
+	if err != nil {
+		return nil, err
+	}
+
+	refresh, err := is.encode(claims, session, "refresh", now.Add(is.opts.RefreshTTL))
+	if err != nil {
+		return nil, err
+	}
+
+	return &TokenPair{
+		AccessToken:  access,
+		RefreshToken: refresh,
+		TokenType:    "Bearer",
+		ExpiresIn:    int64(is.opts.AccessTTL.Seconds()),
+	}, nil
+}
+
+func (is *Issuer) encode(claims map[string]interface{}, session, use string, exp time.Time) (string, error) {
+	jti, err := newID()
+	if err != nil {
+		return "", err
+	}
+
+	c := make(map[string]interface{}, len(claims)+5)
+	for k, v := range claims {
+		c[k] = v
+	}
+	c[jwt.JwtIDKey] = jti
+	c[SessionClaim] = session
+	SetIssuedNow(c)
+	SetExpiry(c, exp)
+	if use != "" {
+		c[TokenUseClaim] = use
+	}
+
+	_, tokenString, err := is.ja.Encode(c)
+	return tokenString, err
+}
+
+// newID returns a random id for the "jti" and "sid" claims
+func newID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+func writeTokenError(w http.ResponseWriter, code, description string) {
+	writeTokenJSON(w, http.StatusBadRequest, map[string]string{
+		"error":             code,
+		"error_description": description,
+	})
+}
+
+func writeTokenJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,186 @@
+package jwtauth_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"go.philip.id/phi/jwtauth"
+)
+
+func TestIssuer(t *testing.T) {
+	ctx := context.Background()
+	tokenAuth := jwtauth.New("HS256", []byte("secretpass"), nil)
+	issuer := jwtauth.NewIssuer(tokenAuth, jwtauth.IssuerOptions{AccessTTL: time.Minute})
+
+	pair, err := issuer.Issue(ctx, map[string]interface{}{"sub": "philip", "tenant_id": "acme"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pair.TokenType != "Bearer" || pair.ExpiresIn != 60 {
+		t.Errorf("unexpected token pair %+v", pair)
+	}
+
+	access, err := jwtauth.VerifyToken(tokenAuth, pair.AccessToken)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if access.Subject() != "philip" || access.JwtID() == "" {
+		t.Errorf("unexpected access token claims %v", access.PrivateClaims())
+	}
+	if _, err := jwtauth.VerifyToken(tokenAuth, pair.RefreshToken); err != jwtauth.ErrUnauthorized {
+		t.Errorf("expected refresh token to be rejected as access token, got %v", err)
+	}
+	if _, err := issuer.Refresh(ctx, pair.AccessToken); err != jwtauth.ErrUnauthorized {
+		t.Errorf("expected access token to be rejected as refresh token, got %v", err)
+	}
+
+	rotated, err := issuer.Refresh(ctx, pair.RefreshToken)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rotated.RefreshToken == pair.RefreshToken {
+		t.Fatal("expected refresh token to be rotated")
+	}
+
+	token, err := jwtauth.VerifyToken(tokenAuth, rotated.AccessToken)
+	if err != nil {
+		t.Fatal(err)
+	}
+	session, _ := access.Get(jwtauth.SessionClaim)
+	if sid, _ := token.Get(jwtauth.SessionClaim); sid == nil || sid != session {
+		t.Errorf("expected rotated tokens to keep the session, got %v", sid)
+	}
+	if token.Subject() != "philip" || token.PrivateClaims()["tenant_id"] != "acme" {
+		t.Errorf("expected rotated tokens to keep the claims, got %v", token.PrivateClaims())
+	}
+
+	// reusing the rotated refresh token revokes the session
+	if _, err := issuer.Refresh(ctx, pair.RefreshToken); err != jwtauth.ErrTokenReused {
+		t.Fatalf("expected ErrTokenReused, got %v", err)
+	}
+	if _, err := jwtauth.VerifyToken(tokenAuth, rotated.AccessToken); err != jwtauth.ErrRevoked {
+		t.Errorf("expected access token of reused session to be revoked, got %v", err)
+	}
+	if _, err := issuer.Refresh(ctx, rotated.RefreshToken); err != jwtauth.ErrRevoked {
+		t.Errorf("expected refresh token of reused session to be revoked, got %v", err)
+	}
+
+	// logout
+	pair, _ = issuer.Issue(ctx, map[string]interface{}{"sub": "philip"})
+	if err := issuer.RevokeSession(ctx, pair.AccessToken); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := issuer.Refresh(ctx, pair.RefreshToken); err != jwtauth.ErrRevoked {
+		t.Errorf("expected refresh token of revoked session to be rejected, got %v", err)
+	}
+
+	// single tokens are revoked by jti
+	pair, _ = issuer.Issue(ctx, map[string]interface{}{"sub": "philip"})
+	access, _ = jwtauth.VerifyToken(tokenAuth, pair.AccessToken)
+	if err := tokenAuth.Revoke(ctx, access); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := jwtauth.VerifyToken(tokenAuth, pair.AccessToken); err != jwtauth.ErrRevoked {
+		t.Errorf("expected revoked access token to be rejected, got %v", err)
+	}
+	if _, err := issuer.Refresh(ctx, pair.RefreshToken); err != nil {
+		t.Errorf("expected refresh token to outlive its revoked access token, got %v", err)
+	}
+
+	if err := jwtauth.New("HS256", []byte("secretpass"), nil).Revoke(ctx, access); !errors.Is(err, jwtauth.ErrNoRevocationStore) {
+		t.Errorf("expected ErrNoRevocationStore, got %v", err)
+	}
+}
+
+func TestRefreshHandler(t *testing.T) {
+	tokenAuth := jwtauth.New("HS256", []byte("secretpass"), nil)
+	issuer := jwtauth.NewIssuer(tokenAuth, jwtauth.IssuerOptions{})
+
+	ts := httptest.NewServer(issuer.RefreshHandler())
+	defer ts.Close()
+
+	pair, _ := issuer.Issue(context.Background(), map[string]interface{}{"sub": "philip"})
+
+	h := http.Header{}
+	h.Set("Content-Type", "application/json")
+	status, resp := testRequest(t, ts, "POST", "/", h, strings.NewReader(`{"refresh_token":"`+pair.RefreshToken+`"}`))
+	if status != 200 {
+		t.Fatalf("expected status 200, got %d: %s", status, resp)
+	}
+
+	var rotated jwtauth.TokenPair
+	if err := json.Unmarshal([]byte(resp), &rotated); err != nil || rotated.RefreshToken == "" {
+		t.Fatalf("unexpected response %s", resp)
+	}
+
+	h.Set("Content-Type", "application/x-www-form-urlencoded")
+	form := url.Values{"refresh_token": {rotated.RefreshToken}}.Encode()
+	if status, resp := testRequest(t, ts, "POST", "/", h, strings.NewReader(form)); status != 200 {
+		t.Errorf("expected status 200 for form body, got %d: %s", status, resp)
+	}
+
+	for _, body := range []string{"", form, "refresh_token=garbage"} {
+		status, resp := testRequest(t, ts, "POST", "/", h, strings.NewReader(body))
+		if status != 400 || !strings.Contains(resp, `"error":"invalid_`) {
+			t.Errorf("expected invalid grant for %q, got %d: %s", body, status, resp)
+		}
+	}
+}
+
+// failingStore is a RevocationStore whose database is down
+type failingStore struct {
+	jwtauth.RevocationStore
+}
+
+func (failingStore) IsRevoked(ctx context.Context, id string) (bool, error) {
+	return false, errors.New("connection refused")
+}
+
+func TestRefreshHandlerStoreError(t *testing.T) {
+	tokenAuth := jwtauth.New("HS256", []byte("secretpass"), nil)
+	tokenAuth.SetRevocationStore(failingStore{jwtauth.NewMemoryRevocationStore()})
+	issuer := jwtauth.NewIssuer(tokenAuth, jwtauth.IssuerOptions{})
+
+	ts := httptest.NewServer(issuer.RefreshHandler())
+	defer ts.Close()
+
+	pair, _ := issuer.Issue(context.Background(), map[string]interface{}{"sub": "philip"})
+
+	h := http.Header{}
+	h.Set("Content-Type", "application/json")
+	status, resp := testRequest(t, ts, "POST", "/", h, strings.NewReader(`{"refresh_token":"`+pair.RefreshToken+`"}`))
+	if status != 500 || !strings.Contains(resp, `"error":"server_error"`) {
+		t.Errorf("expected server error, got %d: %s", status, resp)
+	}
+}
+
+func TestIssuerSharedAuth(t *testing.T) {
+	tokenAuth := jwtauth.New("HS256", []byte("secretpass"), nil)
+	_, tokenString, _ := tokenAuth.Encode(map[string]interface{}{"sub": "philip", "jti": "1"})
+
+	// Issuers may be created while the JWTAuth verifies requests
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 10; i++ {
+			jwtauth.NewIssuer(tokenAuth, jwtauth.IssuerOptions{})
+		}
+	}()
+	for i := 0; i < 10; i++ {
+		if _, err := jwtauth.VerifyToken(tokenAuth, tokenString); err != nil {
+			t.Fatal(err)
+		}
+	}
+	<-done
+
+	if tokenAuth.RevocationStore() == nil {
+		t.Error("expected NewIssuer to set a revocation store")
+	}
+}
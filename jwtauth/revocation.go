@@ -0,0 +1,124 @@
+package jwtauth
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/lestrrat-go/jwx/v2/jwt"
+)
+
+// RevocationStore keeps the ids of revoked tokens and sessions until they
+// expire, see SetRevocationStore. Implementations backed by a shared
+// database revoke tokens across instances.
+type RevocationStore interface {
+	// Revoke revokes the id until expiresAt, it reports whether the id was
+	// revoked already. The check has to be atomic to detect concurrent reuse
+	// of refresh tokens.
+	Revoke(ctx context.Context, id string, expiresAt time.Time) (revoked bool, err error)
+
+	// IsRevoked reports whether the id is revoked.
+	IsRevoked(ctx context.Context, id string) (bool, error)
+}
+
+// SetRevocationStore makes VerifyToken reject tokens whose "jti" or session
+// ("sid") is revoked in the store. It is safe to replace the store while
+// serving requests.
+func (ja *JWTAuth) SetRevocationStore(store RevocationStore) {
+	ja.mu.Lock()
+	defer ja.mu.Unlock()
+
+	ja.revocations = store
+}
+
+// RevocationStore returns the store of SetRevocationStore.
+func (ja *JWTAuth) RevocationStore() RevocationStore {
+	ja.mu.RLock()
+	defer ja.mu.RUnlock()
+
+	return ja.revocations
+}
+
+// Revoke revokes the token by its "jti" until it expires.
+func (ja *JWTAuth) Revoke(ctx context.Context, token jwt.Token) error {
+	store := ja.RevocationStore()
+	if store == nil {
+		return ErrNoRevocationStore
+	}
+	if token.JwtID() == "" {
+		return ErrNoTokenID
+	}
+
+	_, err := store.Revoke(ctx, token.JwtID(), token.Expiration())
+	return err
+}
+
+// revoked reports whether the token or its session is revoked, tokens are
+// rejected if the store fails
+func (ja *JWTAuth) revoked(ctx context.Context, token jwt.Token) bool {
+	store := ja.RevocationStore()
+	if store == nil {
+		return false
+	}
+
+	ids := []string{token.JwtID()}
+	if sid, ok := token.Get(SessionClaim); ok {
+		sid, _ := sid.(string)
+		ids = append(ids, sid)
+	}
+
+	for _, id := range ids {
+		if id == "" {
+			continue
+		}
+		if revoked, err := store.IsRevoked(ctx, id); revoked || err != nil {
+			return true
+		}
+	}
+
+	return false
+}
+
+// MemoryRevocationStore is a RevocationStore of a single instance, revoked
+// ids are forgotten once they expire.
+type MemoryRevocationStore struct {
+	mu        sync.Mutex
+	revoked   map[string]time.Time
+	lastPrune time.Time
+}
+
+// NewMemoryRevocationStore returns an empty MemoryRevocationStore.
+func NewMemoryRevocationStore() *MemoryRevocationStore {
+	return &MemoryRevocationStore{revoked: map[string]time.Time{}}
+}
+
+func (s *MemoryRevocationStore) Revoke(_ context.Context, id string, expiresAt time.Time) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	if now.Sub(s.lastPrune) > time.Minute {
+		for revokedID, exp := range s.revoked {
+			if !exp.IsZero() && now.After(exp) {
+				delete(s.revoked, revokedID)
+			}
+		}
+		s.lastPrune = now
+	}
+
+	exp, revoked := s.revoked[id]
+	revoked = revoked && (exp.IsZero() || now.Before(exp))
+	if !revoked || expiresAt.IsZero() || (!exp.IsZero() && expiresAt.After(exp)) {
+		s.revoked[id] = expiresAt
+	}
+
+	return revoked, nil
+}
+
+func (s *MemoryRevocationStore) IsRevoked(_ context.Context, id string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	exp, ok := s.revoked[id]
+	return ok && (exp.IsZero() || time.Now().Before(exp)), nil
+}
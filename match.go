@@ -0,0 +1,308 @@
+package phi
+
+import (
+	"mime"
+	"net/http"
+	"strings"
+)
+
+// Matcher is a request predicate of a route registered via Mux.Matching,
+// besides its method and path.
+type Matcher interface {
+	// Match reports whether the request satisfies the predicate.
+	Match(r *http.Request) bool
+
+	// Status is the status code responded with when no endpoint of the
+	// route matches because of this predicate, f.e. 404 or 415.
+	Status() int
+}
+
+// MatcherFunc is an adapter to use functions as Matcher, requests not
+// matching any endpoint are responded to with 404.
+type MatcherFunc func(r *http.Request) bool
+
+// Match calls f(r).
+func (f MatcherFunc) Match(r *http.Request) bool {
+	return f(r)
+}
+
+// Status returns 404.
+func (f MatcherFunc) Status() int {
+	return http.StatusNotFound
+}
+
+type matcher struct {
+	match  func(r *http.Request) bool
+	status int
+}
+
+func (m matcher) Match(r *http.Request) bool {
+	return m.match(r)
+}
+
+func (m matcher) Status() int {
+	return m.status
+}
+
+// Header matches requests with the header `key` set to `value`, or set at
+// all if value is empty.
+func Header(key, value string) Matcher {
+	return MatcherFunc(func(r *http.Request) bool {
+		values := r.Header.Values(key)
+		if value == "" {
+			return len(values) > 0
+		}
+
+		for _, v := range values {
+			if v == value {
+				return true
+			}
+		}
+		return false
+	})
+}
+
+// Query matches requests with the query parameter `key` set to `value`, or
+// set at all if value is empty.
+func Query(key, value string) Matcher {
+	return MatcherFunc(func(r *http.Request) bool {
+		values, ok := r.URL.Query()[key]
+		if value == "" {
+			return ok
+		}
+
+		for _, v := range values {
+			if v == value {
+				return true
+			}
+		}
+		return false
+	})
+}
+
+// ContentType matches requests whose body has one of the media types, which
+// may be ranges like "image/*". Requests matching no endpoint because of it
+// are responded to with 415.
+func ContentType(mediaTypes ...string) Matcher {
+	return matcher{status: http.StatusUnsupportedMediaType, match: func(r *http.Request) bool {
+		mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+		if err != nil {
+			return false
+		}
+
+		for _, t := range mediaTypes {
+			if matchMediaRange(strings.ToLower(t), mediaType) {
+				return true
+			}
+		}
+		return false
+	}}
+}
+
+// Accept matches requests accepting one of the media types. Requests matching
+// no endpoint because of it are responded to with 406.
+func Accept(mediaTypes ...string) Matcher {
+	return matcher{status: http.StatusNotAcceptable, match: func(r *http.Request) bool {
+		ranges := parseAccept(r.Header.Get("Accept"))
+
+		for _, t := range mediaTypes {
+			t = strings.ToLower(t)
+
+			// the most specific range decides, f.e. "*/*, text/csv;q=0"
+			q, specificity := 0.0, -1
+			for _, ar := range ranges {
+				if !matchMediaRange(ar.mediaType, t) {
+					continue
+				}
+
+				s := 0
+				if ar.mediaType == t {
+					s = 2
+				} else if ar.mediaType != "*/*" {
+					s = 1
+				}
+				if s > specificity {
+					q, specificity = ar.q, s
+				}
+			}
+
+			if q > 0 {
+				return true
+			}
+		}
+		return false
+	}}
+}
+
+// Matching adds inline request predicates for endpoint handlers, the
+// endpoints are only served if all of them match:
+//
+//	r.Matching(phi.Header("X-Version", "2")).Get("/users", listUsersV2)
+//	r.Matching(phi.Query("format", "csv")).Get("/users", exportUsers)
+//	r.Get("/users", listUsers)
+//
+// Endpoints with predicates of the same method and path are tried in the
+// order they were registered, the endpoint without predicates serves the
+// remaining requests. Without such endpoint the request is responded to with
+// the status of the first predicate that failed of the closest endpoint,
+// see Matcher.
+func (mx *Mux) Matching(matchers ...Matcher) Router {
+	im := mx.With().(*Mux)
+	im.matchers = append(im.matchers, matchers...)
+	return im
+}
+
+// matchRoute is an endpoint handler of a route with predicates
+type matchRoute struct {
+	matchers []Matcher
+	handler  http.Handler
+
+	// inline mux the endpoint was registered with, see replaceVariant
+	owner *Mux
+}
+
+// matchHandler serves the first endpoint of a route whose predicates match
+// the request
+type matchHandler struct {
+	routes   []matchRoute
+	fallback http.Handler
+
+	// mux responding to requests matching no endpoint
+	mux *Mux
+}
+
+func (mh *matchHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	status, closest := http.StatusNotFound, -1
+
+	for _, route := range mh.routes {
+		failed, s := 0, 0
+		for _, m := range route.matchers {
+			if !m.Match(r) {
+				if failed == 0 {
+					s = m.Status()
+				}
+				failed++
+			}
+		}
+
+		if failed == 0 {
+			route.handler.ServeHTTP(w, r)
+			return
+		}
+		if closest < 0 || failed < closest {
+			status, closest = s, failed
+		}
+	}
+
+	if mh.fallback != nil {
+		mh.fallback.ServeHTTP(w, r)
+		return
+	}
+
+	switch status {
+	case http.StatusNotFound:
+		mh.mux.NotFoundHandler().ServeHTTP(w, r)
+	case http.StatusNotAcceptable:
+		HandleError(w, r, NotAcceptableError(r.Header.Get("Accept")))
+	case http.StatusUnsupportedMediaType:
+		HandleError(w, r, UnsupportedMediaTypeError(r.Header.Get("Content-Type")))
+	default:
+		HandleError(w, r, &Error{
+			Error:      "noMatchingRoute",
+			Message:    http.StatusText(status),
+			StatusCode: status,
+		})
+	}
+}
+
+// handlers returns the endpoint handlers in the order they are tried
+func (mh *matchHandler) handlers() []http.Handler {
+	hs := make([]http.Handler, 0, len(mh.routes)+1)
+	for _, route := range mh.routes {
+		hs = append(hs, route.handler)
+	}
+	if mh.fallback != nil {
+		hs = append(hs, mh.fallback)
+	}
+
+	return hs
+}
+
+// mergeMatchHandler combines the endpoint handler `h` with the previous
+// handler of the same method and path if either has predicates, otherwise
+// h replaces it
+func mergeMatchHandler(prev, h http.Handler) http.Handler {
+	pmh, pok := prev.(*matchHandler)
+	mh, ok := h.(*matchHandler)
+
+	switch {
+	case !pok && !ok:
+		return h
+	case !pok:
+		if prev == nil {
+			return h
+		}
+		return &matchHandler{routes: mh.routes, fallback: prev, mux: mh.mux}
+	}
+
+	merged := &matchHandler{
+		routes:   append([]matchRoute{}, pmh.routes...),
+		fallback: pmh.fallback,
+		mux:      pmh.mux,
+	}
+	if ok {
+		merged.routes = append(merged.routes, mh.routes...)
+	} else {
+		merged.fallback = h
+	}
+
+	return merged
+}
+
+// replaceVariant replaces the endpoints of the handler `h` registered by the
+// inline mux `owner` with `nh`, or its endpoint without predicates if owner
+// is nil. A nil `nh` removes them. Other endpoints of the same method and
+// path are kept, the handler left is nil if none remains. It reports whether
+// h had such endpoints.
+func replaceVariant(h http.Handler, owner *Mux, nh http.Handler) (http.Handler, bool) {
+	mh, ok := h.(*matchHandler)
+	if !ok {
+		if owner != nil || h == nil {
+			return h, false
+		}
+		return nh, true
+	}
+
+	if owner == nil {
+		if mh.fallback == nil {
+			return h, false
+		}
+		if nh == nil && len(mh.routes) == 0 {
+			return nil, true
+		}
+		return &matchHandler{routes: mh.routes, fallback: nh, mux: mh.mux}, true
+	}
+
+	var routes []matchRoute
+	replaced := false
+	for _, route := range mh.routes {
+		if route.owner != owner {
+			routes = append(routes, route)
+			continue
+		}
+
+		// the replacement takes the place of the first endpoint of owner
+		if nmh, ok := nh.(*matchHandler); ok && !replaced {
+			routes = append(routes, nmh.routes...)
+		}
+		replaced = true
+	}
+
+	switch {
+	case !replaced:
+		return h, false
+	case len(routes) == 0:
+		return mh.fallback, true
+	}
+	return &matchHandler{routes: routes, fallback: mh.fallback, mux: mh.mux}, true
+}
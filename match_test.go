@@ -0,0 +1,169 @@
+package phi
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func TestMatching(t *testing.T) {
+	text := func(s string) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(s))
+		}
+	}
+
+	var middlewareCalls int
+	counter := func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			middlewareCalls++
+			next.ServeHTTP(w, r)
+		})
+	}
+
+	r := NewRouter()
+	r.NotFound(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(404)
+		w.Write([]byte("nothing here"))
+	})
+
+	r.With(counter).Matching(Header("X-Version", "2"), Query("format", "csv")).Get("/users", text("v2 csv"))
+	r.Matching(Header("X-Version", "2")).Get("/users", text("v2"))
+	r.Get("/users", text("v1"))
+	r.Matching(Query("format", "")).Get("/users", text("formatted"))
+
+	r.Group(func(r Router) {
+		r = r.Matching(Header("X-Version", "2"))
+		r.Matching(ContentType("application/json")).Post("/orders", text("json order"))
+		r.Matching(ContentType("text/*")).Post("/orders", text("text order"))
+	})
+
+	r.Matching(Accept("text/csv")).Get("/export", text("csv"))
+	r.Matching(Accept("application/json")).Get("/export", text("json"))
+
+	tests := []struct {
+		method string
+		path   string
+		header http.Header
+		status int
+		body   string
+	}{
+		{"GET", "/users", nil, 200, "v1"},
+		{"GET", "/users", http.Header{"X-Version": {"2"}}, 200, "v2"},
+		{"GET", "/users?format=csv", http.Header{"X-Version": {"2"}}, 200, "v2 csv"},
+		{"GET", "/users?format=xml", nil, 200, "formatted"},
+		{"POST", "/users", nil, 405, ""},
+
+		{"POST", "/orders", http.Header{"X-Version": {"2"}, "Content-Type": {"application/json; charset=utf-8"}}, 200, "json order"},
+		{"POST", "/orders", http.Header{"X-Version": {"2"}, "Content-Type": {"text/plain"}}, 200, "text order"},
+		{"POST", "/orders", http.Header{"X-Version": {"2"}, "Content-Type": {"application/xml"}}, 415, ""},
+		{"POST", "/orders", http.Header{"Content-Type": {"application/json"}}, 404, "nothing here"},
+		{"POST", "/orders", nil, 404, "nothing here"},
+		{"GET", "/orders", nil, 405, ""},
+
+		{"GET", "/export", nil, 200, "csv"},
+		{"GET", "/export", http.Header{"Accept": {"application/json"}}, 200, "json"},
+		{"GET", "/export", http.Header{"Accept": {"*/*, text/csv;q=0"}}, 200, "json"},
+		{"GET", "/export", http.Header{"Accept": {"text/html"}}, 406, ""},
+	}
+
+	for _, tt := range tests {
+		req := httptest.NewRequest(tt.method, tt.path, nil)
+		for k, v := range tt.header {
+			req.Header[k] = v
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+
+		if w.Code != tt.status || (tt.body != "" && w.Body.String() != tt.body) {
+			t.Errorf("%s %s %v: expected %d %q, got %d %q", tt.method, tt.path, tt.header, tt.status, tt.body, w.Code, w.Body.String())
+		}
+	}
+
+	if middlewareCalls != 1 {
+		t.Errorf("expected inline middlewares to run for matching requests only, got %d calls", middlewareCalls)
+	}
+}
+
+func TestMatchingWalk(t *testing.T) {
+	r := NewRouter()
+	r.Matching(Header("X-Version", "2")).Get("/users", func(w http.ResponseWriter, r *http.Request) {})
+	r.Get("/users", func(w http.ResponseWriter, r *http.Request) {})
+	r.With(func(next http.Handler) http.Handler { return next }).Matching(MatcherFunc(func(r *http.Request) bool {
+		return true
+	})).Post("/users", func(w http.ResponseWriter, r *http.Request) {})
+
+	var got []string
+	Walk(r, func(method string, route string, handler http.Handler, middlewares ...func(http.Handler) http.Handler) error {
+		if _, ok := handler.(*ChainHandler); ok {
+			t.Errorf("expected endpoint of inline middlewares, got chain")
+		}
+		got = append(got, method+" "+route)
+		return nil
+	})
+	sort.Strings(got)
+
+	if expected := "GET /users, GET /users, POST /users"; strings.Join(got, ", ") != expected {
+		t.Errorf("expected routes %s, got %v", expected, got)
+	}
+}
+
+func TestMatchingRemoveReplace(t *testing.T) {
+	text := func(s string) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(s))
+		}
+	}
+
+	r := NewRouter()
+	v2 := r.Matching(Header("X-Version", "2")).(*Mux)
+	v3 := r.Matching(Header("X-Version", "3")).(*Mux)
+	v2.Get("/users", text("v2"))
+	v3.Get("/users", text("v3"))
+	r.Get("/users", text("v1"))
+
+	get := func(version string) (int, string) {
+		req := httptest.NewRequest("GET", "/users", nil)
+		if version != "" {
+			req.Header.Set("X-Version", version)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w.Code, w.Body.String()
+	}
+
+	if !v2.Replace("GET", "/users", text("new v2")) {
+		t.Error("expected v2 endpoint to be replaced")
+	}
+	if r.Matching(Header("X-Version", "4")).(*Mux).Replace("GET", "/users", text("v4")) {
+		t.Error("expected unknown variant not to be replaced")
+	}
+	if !r.Replace("GET", "/users", text("new v1")) {
+		t.Error("expected endpoint without predicates to be replaced")
+	}
+
+	for version, expected := range map[string]string{"": "new v1", "2": "new v2", "3": "v3"} {
+		if _, body := get(version); body != expected {
+			t.Errorf("version %q: expected %q, got %q", version, expected, body)
+		}
+	}
+
+	if !r.Remove("GET", "/users") || r.Remove("GET", "/users") {
+		t.Error("expected endpoint without predicates to be removed once")
+	}
+	if status, _ := get(""); status != 404 {
+		t.Errorf("expected 404 without fallback, got %d", status)
+	}
+	if _, body := get("3"); body != "v3" {
+		t.Errorf("expected v3 to be kept, got %q", body)
+	}
+
+	if !v2.Remove("GET", "/users") || !v3.Remove("GET", "/users") {
+		t.Error("expected variants to be removed")
+	}
+	if len(r.Routes()) != 0 {
+		t.Errorf("expected route to be removed, got %v", r.Routes())
+	}
+}
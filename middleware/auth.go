@@ -8,13 +8,12 @@ import (
 	"github.com/lestrrat-go/jwx/v2/jwt"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.philip.id/phi"
+	"go.philip.id/phi/auth"
 	"go.philip.id/phi/jwtauth"
 )
 
-type Token struct {
-	ID      string `json:"id"`
-	Subject string `json:"subject"`
-}
+// Token is the identity of authenticated requests, see auth.Token
+type Token = auth.Token
 
 type TOKEN_TYPE string
 
@@ -41,6 +40,9 @@ var (
 // SetUnauthorizedFunc sets the function to be called when a request is unauthorized
 //
 // default is phi.Unauthorized
+//
+// Deprecated: Authenticate responds through the error handler of the router,
+// see phi.Mux.ErrorHandler.
 func SetUnauthorizedFunc(fn func() *phi.Error) {
 	unauthorizedFunc = fn
 }
@@ -60,6 +62,8 @@ func SetUnauthorizedFunc(fn func() *phi.Error) {
 //			Subject:  a.Subject,
 //		}
 //	}
+//
+// Deprecated: use Authenticate with an auth.Basic configured per router.
 func SetTokenCheckFunc(fn func(username, password string) (*Token, error)) {
 	tokenCheckFunc = fn
 }
@@ -98,7 +102,7 @@ func GetUserID(r *phi.Request) (*primitive.ObjectID, *phi.Error) {
 // unauthorized response can be set via SetTokenCheckFunc
 //
 // Can be used for endpoints which are gonna be used for a frontend and from an api
-// at the same time
+// at the same time, see Authenticate to configure the credentials and their order
 //
 // Tokens can be extracted like one of the following:
 //
@@ -118,7 +122,7 @@ func JWTOrAPIAuth(next http.Handler) http.Handler {
 			return
 		}
 
-		phi.ErrorHandler(w, r, unauthorizedFunc())
+		phi.HandleError(w, r, unauthorizedFunc())
 	})
 }
 
@@ -159,7 +163,7 @@ func JWTAuth(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		token, err := checkBearer(r)
 		if err != nil {
-			phi.ErrorHandler(w, r, unauthorizedFunc())
+			phi.HandleError(w, r, unauthorizedFunc())
 			return
 		}
 
@@ -200,7 +204,7 @@ func APIAuth(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		token, err := checkBasic(r)
 		if err != nil {
-			phi.ErrorHandler(w, r, unauthorizedFunc())
+			phi.HandleError(w, r, unauthorizedFunc())
 			return
 		}
 
@@ -234,15 +238,7 @@ func checkBearer(r *http.Request) (*Token, error) {
 	}
 
 	if token != nil && jwt.Validate(token) == nil {
-		t := &Token{}
-
-		// Get only ID for now
-		t.ID = token.JwtID()
-		t.Subject = token.Subject()
-
-		// TODO: add more claim parsing here
-
-		return t, nil
+		return tokenFromJWT(token)
 	}
 
 	return nil, errors.New("token invalid")
@@ -0,0 +1,77 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"go.philip.id/phi"
+	"go.philip.id/phi/auth"
+)
+
+// Authenticate tries the authenticators in order and stores the token of
+// the first one finding credentials under TOKEN_CONTEXT. Requests without
+// credentials or with invalid ones are unauthorized, answered with
+// phi.Unauthorized through the error handler of the router:
+//
+//	r.Use(middleware.Authenticate(
+//		&auth.APIKey{Header: "X-API-Key", Lookup: apiKeys.Find},
+//		&auth.Basic{Lookup: users.CheckPassword},
+//		&auth.Bearer{JWTAuth: tokenAuth},
+//		&auth.ClientCert{},
+//		&auth.Cookie{Name: "session", Lookup: sessions.Find},
+//	))
+//
+// The authenticators are configured per instance, routers may use
+// different credential stores.
+func Authenticate(authenticators ...auth.Authenticator) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			token, err := authenticate(r, authenticators)
+			if err != nil {
+				phi.HandleError(w, r, phi.Unauthorized())
+				return
+			}
+
+			ctx := context.WithValue(r.Context(), TOKEN_CONTEXT, *token)
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// Same as Authenticate but continues without adding the token if unauthorized
+//
+// Can be used for cases where an authenticated user will receive a different
+// response but still has access to the ressource
+func AuthenticateOptional(authenticators ...auth.Authenticator) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			token, err := authenticate(r, authenticators)
+			if err != nil {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			ctx := context.WithValue(r.Context(), TOKEN_CONTEXT, *token)
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// authenticate returns the token of the first authenticator finding
+// credentials, the request is rejected by the first one failing
+func authenticate(r *http.Request, authenticators []auth.Authenticator) (*Token, error) {
+	for _, a := range authenticators {
+		token, err := a.Authenticate(r)
+		if errors.Is(err, auth.ErrNoCredentials) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		return token, nil
+	}
+
+	return nil, auth.ErrNoCredentials
+}
@@ -0,0 +1,75 @@
+package middleware
+
+import (
+	"context"
+	"net/http/httptest"
+	"testing"
+
+	"go.philip.id/phi"
+	"go.philip.id/phi/auth"
+	"go.philip.id/phi/jwtauth"
+)
+
+func apiKeys(keys map[string]string) *auth.APIKey {
+	return &auth.APIKey{Lookup: func(ctx context.Context, key string) (*Token, error) {
+		if sub, ok := keys[key]; ok {
+			return &Token{Subject: sub}, nil
+		}
+		return nil, auth.ErrInvalidCredentials
+	}}
+}
+
+func TestAuthenticate(t *testing.T) {
+	tokenAuth := jwtauth.New("HS256", []byte("secretpass"), nil)
+	_, jwt, _ := tokenAuth.Encode(map[string]interface{}{"sub": "philip"})
+
+	whoami := func(res *phi.Response, req *phi.Request) *phi.Error {
+		token := GetToken(req)
+		if token == nil {
+			return res.Response([]byte("anonymous"), "text/plain")
+		}
+		return res.Response([]byte(token.Method+":"+token.Subject), "text/plain")
+	}
+
+	// routers with credential stores of their own
+	r := phi.NewRouter()
+	r.Route("/public", func(r phi.Router) {
+		r.Use(AuthenticateOptional(apiKeys(map[string]string{"k1": "public"})))
+		r.GET("/", whoami)
+	})
+	r.Route("/admin", func(r phi.Router) {
+		r.Use(Authenticate(apiKeys(map[string]string{"k2": "admin"}), &auth.Bearer{JWTAuth: tokenAuth}))
+		r.GET("/", whoami)
+	})
+
+	tests := []struct {
+		name   string
+		path   string
+		header map[string]string
+		status int
+		body   string
+	}{
+		{"api key", "/admin/", map[string]string{"X-API-Key": "k2"}, 200, "apikey:admin"},
+		{"key of other router", "/admin/", map[string]string{"X-API-Key": "k1"}, 401, ""},
+		{"bearer", "/admin/", map[string]string{"Authorization": "Bearer " + jwt}, 200, "bearer:philip"},
+		{"first authenticator wins", "/admin/", map[string]string{"X-API-Key": "k2", "Authorization": "Bearer " + jwt}, 200, "apikey:admin"},
+		{"invalid credentials stop", "/admin/", map[string]string{"X-API-Key": "k3", "Authorization": "Bearer " + jwt}, 401, ""},
+		{"no credentials", "/admin/", nil, 401, ""},
+		{"optional", "/public/", map[string]string{"X-API-Key": "k1"}, 200, "apikey:public"},
+		{"optional anonymous", "/public/", map[string]string{"X-API-Key": "k2"}, 200, "anonymous"},
+	}
+
+	for _, tt := range tests {
+		req := httptest.NewRequest("GET", tt.path, nil)
+		for k, v := range tt.header {
+			req.Header.Set(k, v)
+		}
+
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+
+		if w.Code != tt.status || (tt.body != "" && w.Body.String() != tt.body) {
+			t.Errorf("%s: expected %d %q, got %d %q", tt.name, tt.status, tt.body, w.Code, w.Body.String())
+		}
+	}
+}
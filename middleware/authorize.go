@@ -0,0 +1,112 @@
+package middleware
+
+import (
+	"net/http"
+	"reflect"
+	"runtime"
+	"strings"
+
+	"go.philip.id/phi"
+)
+
+// RequireScopes responds with 403 Forbidden unless the token of the request
+// carries all of the scopes. It has to follow one of the auth middlewares,
+// requests without token are unauthorized:
+//
+//	r.Use(middleware.JWTAuth)
+//	r.With(middleware.RequireScopes("orders:write")).Post("/orders", createOrder)
+//
+// The scopes are reported for the routes it guards, see
+// phi.WalkRequirements.
+func RequireScopes(scopes ...string) func(next http.Handler) http.Handler {
+	return guard(phi.Requirements{Scopes: scopes}, func(token *Token) bool {
+		for _, scope := range scopes {
+			if !contains(token.Scopes, scope) {
+				return false
+			}
+		}
+		return true
+	})
+}
+
+// RequireRoles responds with 403 Forbidden unless the token of the request
+// carries one of the roles, see RequireScopes.
+func RequireRoles(roles ...string) func(next http.Handler) http.Handler {
+	return guard(phi.Requirements{Roles: roles}, func(token *Token) bool {
+		for _, role := range roles {
+			if contains(token.Roles, role) {
+				return true
+			}
+		}
+		return false
+	})
+}
+
+// Require responds with 403 Forbidden unless the policy allows the token of
+// the request, see RequireScopes. The policy is reported by its function
+// name:
+//
+//	func ownsTenant(token *middleware.Token) bool {
+//		return token.Claims["tenant_id"] == "acme"
+//	}
+//
+//	r.With(middleware.Require(ownsTenant)).Get("/billing", billing)
+func Require(policy func(token *Token) bool) func(next http.Handler) http.Handler {
+	return guard(phi.Requirements{Policies: []string{policyName(policy)}}, policy)
+}
+
+func guard(requirements phi.Requirements, allow func(token *Token) bool) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return &guardHandler{next: next, requirements: requirements, allow: allow}
+	}
+}
+
+// guardHandler implements phi.GuardHandler
+type guardHandler struct {
+	next         http.Handler
+	requirements phi.Requirements
+	allow        func(token *Token) bool
+}
+
+func (h *guardHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	token, ok := r.Context().Value(TOKEN_CONTEXT).(Token)
+	if !ok {
+		phi.HandleError(w, r, phi.Unauthorized())
+		return
+	}
+
+	if !h.allow(&token) {
+		phi.HandleError(w, r, phi.Forbidden())
+		return
+	}
+
+	h.next.ServeHTTP(w, r)
+}
+
+func (h *guardHandler) Requirements() phi.Requirements {
+	return h.requirements
+}
+
+// policyName returns the name of the policy function without its package
+// path, f.e. "main.ownsTenant"
+func policyName(policy func(token *Token) bool) string {
+	fn := runtime.FuncForPC(reflect.ValueOf(policy).Pointer())
+	if fn == nil {
+		return "policy"
+	}
+
+	name := fn.Name()
+	if idx := strings.LastIndexByte(name, '/'); idx >= 0 {
+		name = name[idx+1:]
+	}
+	return name
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
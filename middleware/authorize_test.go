@@ -0,0 +1,132 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"go.philip.id/phi"
+)
+
+func withToken(token *Token) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if token != nil {
+				r = r.WithContext(context.WithValue(r.Context(), TOKEN_CONTEXT, *token))
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+func isOwner(token *Token) bool {
+	return token.Claims["owner"] == true
+}
+
+func TestRequire(t *testing.T) {
+	routes := func(token *Token) *phi.Mux {
+		r := phi.NewRouter()
+		r.Use(withToken(token))
+		r.With(RequireScopes("orders:read", "orders:write")).Post("/orders", func(w http.ResponseWriter, r *http.Request) {})
+		r.With(RequireRoles("admin", "support")).Get("/admin", func(w http.ResponseWriter, r *http.Request) {})
+		r.With(Require(isOwner)).Delete("/account", func(w http.ResponseWriter, r *http.Request) {})
+		return r
+	}
+
+	tests := []struct {
+		name   string
+		token  *Token
+		method string
+		path   string
+		status int
+	}{
+		{"all scopes", &Token{Scopes: []string{"orders:write", "orders:read"}}, "POST", "/orders", 200},
+		{"missing scope", &Token{Scopes: []string{"orders:read"}}, "POST", "/orders", 403},
+		{"without token", nil, "POST", "/orders", 401},
+		{"one of the roles", &Token{Roles: []string{"support"}}, "GET", "/admin", 200},
+		{"other role", &Token{Roles: []string{"user"}}, "GET", "/admin", 403},
+		{"policy allows", &Token{Claims: map[string]interface{}{"owner": true}}, "DELETE", "/account", 200},
+		{"policy denies", &Token{}, "DELETE", "/account", 403},
+	}
+
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		routes(tt.token).ServeHTTP(w, httptest.NewRequest(tt.method, tt.path, nil))
+		if w.Code != tt.status {
+			t.Errorf("%s: expected status %d, got %d: %s", tt.name, tt.status, w.Code, w.Body.String())
+		}
+	}
+
+	requirements := map[string]phi.Requirements{}
+	phi.WalkRequirements(routes(nil), func(method, route string, handler http.Handler, rq phi.Requirements) error {
+		requirements[method+" "+route] = rq
+		return nil
+	})
+
+	expected := map[string]phi.Requirements{
+		"POST /orders":    {Scopes: []string{"orders:read", "orders:write"}},
+		"GET /admin":      {Roles: []string{"admin", "support"}},
+		"DELETE /account": {Policies: []string{"middleware.isOwner"}},
+	}
+	if !reflect.DeepEqual(requirements, expected) {
+		t.Errorf("expected requirements %+v, got %+v", expected, requirements)
+	}
+
+	for _, route := range routes(nil).Routes() {
+		if route.Pattern == "/orders" {
+			if rq := phi.HandlerRequirements(route.Handlers["POST"]); len(rq.Scopes) != 2 {
+				t.Errorf("expected scopes of Routes, got %+v", rq)
+			}
+		}
+	}
+}
+
+func TestRequireRecorded(t *testing.T) {
+	calls := 0
+	counted := func(mw func(http.Handler) http.Handler) func(http.Handler) http.Handler {
+		return func(next http.Handler) http.Handler {
+			calls++
+			return mw(next)
+		}
+	}
+
+	r := phi.NewRouter()
+	r.ErrorHandler(func(w http.ResponseWriter, r *http.Request, e *phi.Error) {
+		w.WriteHeader(e.StatusCode)
+		w.Write([]byte("custom " + e.Error))
+	})
+	r.Use(counted(RequireRoles("staff")))
+	r.Route("/orders", func(r phi.Router) {
+		r.Use(counted(RequireScopes("orders:read")))
+		r.Get("/", func(w http.ResponseWriter, r *http.Request) {})
+		r.With(counted(RequireScopes("orders:write"))).Post("/", func(w http.ResponseWriter, r *http.Request) {})
+	})
+
+	registered := calls
+	requirements := map[string]phi.Requirements{}
+	phi.WalkRequirements(r, func(method, route string, handler http.Handler, rq phi.Requirements) error {
+		requirements[method+" "+route] = rq
+		return nil
+	})
+
+	if calls != registered {
+		t.Errorf("expected walking not to call the middlewares, got %d calls after %d", calls, registered)
+	}
+
+	expected := map[string]phi.Requirements{
+		"GET /orders/":  {Scopes: []string{"orders:read"}, Roles: []string{"staff"}},
+		"POST /orders/": {Scopes: []string{"orders:read", "orders:write"}, Roles: []string{"staff"}},
+	}
+	if !reflect.DeepEqual(requirements, expected) {
+		t.Errorf("expected requirements %+v, got %+v", expected, requirements)
+	}
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/orders/", nil))
+	if w.Code != 401 || !strings.HasPrefix(w.Body.String(), "custom ") {
+		t.Errorf("expected 401 of the error handler of the router, got %d: %s", w.Code, w.Body.String())
+	}
+}
@@ -0,0 +1,29 @@
+package middleware
+
+import (
+	"fmt"
+	"net/http"
+
+	"go.philip.id/phi"
+)
+
+// BodyLimit limits request bodies to n bytes. Requests declaring a larger
+// Content-Length are rejected with 413 Request Entity Too Large, reading
+// beyond the limit fails, which phi.Validate, phi.Bind and
+// phi.BindMultipart report with status 413 as well.
+func BodyLimit(n int64) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		fn := func(w http.ResponseWriter, r *http.Request) {
+			if r.ContentLength > n {
+				phi.HandleError(w, r, phi.RequestTooLargeError(fmt.Sprintf("request body exceeds %d bytes", n)))
+				return
+			}
+
+			if r.Body != nil {
+				r.Body = phi.LimitBody(r.Body, n)
+			}
+			next.ServeHTTP(w, r)
+		}
+		return http.HandlerFunc(fn)
+	}
+}
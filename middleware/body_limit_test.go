@@ -0,0 +1,86 @@
+package middleware
+
+import (
+	"bytes"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"go.philip.id/phi"
+)
+
+func TestBodyLimit(t *testing.T) {
+	type body struct {
+		Name string `json:"name"`
+	}
+
+	r := phi.NewRouter()
+	r.Use(BodyLimit(16))
+	r.POST("/", func(w *phi.Response, r *phi.Request) *phi.Error {
+		b, err := phi.Validate[body](r)
+		if err != nil {
+			return err
+		}
+		return w.JSON(b)
+	})
+
+	tests := []struct {
+		name    string
+		body    string
+		chunked bool
+		want    int
+	}{
+		{"within limit", `{"name":"phi"}`, false, http.StatusOK},
+		{"content length", `{"name":"philip jovanovic"}`, false, http.StatusRequestEntityTooLarge},
+		{"chunked", `{"name":"philip jovanovic"}`, true, http.StatusRequestEntityTooLarge},
+	}
+
+	for _, tt := range tests {
+		var content io.Reader = strings.NewReader(tt.body)
+		if tt.chunked {
+			// hide the length of the body
+			content = io.MultiReader(content)
+		}
+
+		req := httptest.NewRequest("POST", "/", content)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+
+		if w.Code != tt.want {
+			t.Errorf("%s: expected status %d, got %d: %s", tt.name, tt.want, w.Code, w.Body.String())
+		}
+	}
+}
+
+func TestBodyLimitMultipart(t *testing.T) {
+	type form struct {
+		Title string `form:"title"`
+	}
+
+	r := phi.NewRouter()
+	r.Use(BodyLimit(64))
+	r.POST("/", func(w *phi.Response, r *phi.Request) *phi.Error {
+		if _, err := phi.BindMultipart[form](r, phi.MultipartOptions{}); err != nil {
+			return err
+		}
+		return w.JSON("ok")
+	})
+
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	mw.WriteField("title", strings.Repeat("x", 128))
+	mw.Close()
+
+	// hide the length of the body
+	req := httptest.NewRequest("POST", "/", io.MultiReader(&body))
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("expected status 413, got %d: %s", w.Code, w.Body.String())
+	}
+}
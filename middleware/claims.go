@@ -0,0 +1,64 @@
+package middleware
+
+import (
+	"sync"
+
+	"github.com/lestrrat-go/jwx/v2/jwt"
+	"go.philip.id/phi"
+	"go.philip.id/phi/auth"
+)
+
+var claimsDecoder struct {
+	sync.RWMutex
+	decode func(token jwt.Token) (interface{}, error)
+}
+
+// SetClaimsType makes the bearer auth middlewares decode the claims of
+// tokens into T, read by handlers with GetClaims. Claims are mapped by the
+// json tags of T, tokens whose claims fail to decode are unauthorized:
+//
+//	type Claims struct {
+//		Subject  string `json:"sub"`
+//		TenantID string `json:"tenant_id"`
+//		Plan     string `json:"plan"`
+//	}
+//
+//	middleware.SetClaimsType[Claims]()
+func SetClaimsType[T any]() {
+	claimsDecoder.Lock()
+	defer claimsDecoder.Unlock()
+
+	claimsDecoder.decode = auth.DecodeClaims[T]()
+}
+
+// GetClaims returns the claims of the token of the request decoded into
+// the type registered with SetClaimsType or auth.Bearer.DecodeClaims, nil
+// if there is no token or the claims are of another type.
+func GetClaims[T any](r *phi.Request) *T {
+	token := GetToken(r)
+	if token == nil {
+		return nil
+	}
+
+	claims, _ := token.TypedClaims.(*T)
+	return claims
+}
+
+// tokenFromJWT maps the claims of a verified jwt to a Token
+func tokenFromJWT(token jwt.Token) (*Token, error) {
+	t := auth.TokenFromJWT(token)
+
+	claimsDecoder.RLock()
+	decode := claimsDecoder.decode
+	claimsDecoder.RUnlock()
+
+	if decode != nil {
+		typed, err := decode(token)
+		if err != nil {
+			return nil, err
+		}
+		t.TypedClaims = typed
+	}
+
+	return t, nil
+}
@@ -0,0 +1,86 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	"go.philip.id/phi"
+	"go.philip.id/phi/jwtauth"
+)
+
+type tenantClaims struct {
+	Subject  string `json:"sub"`
+	TenantID string `json:"tenant_id"`
+	Seats    int    `json:"seats"`
+}
+
+func TestClaims(t *testing.T) {
+	tokenAuth := jwtauth.New("HS256", []byte("ultra_secret_key"), nil)
+
+	SetClaimsType[tenantClaims]()
+	defer func() { claimsDecoder.decode = nil }()
+
+	var token *Token
+	var claims *tenantClaims
+
+	r := phi.NewRouter()
+	r.Use(jwtauth.Verifier(tokenAuth), JWTAuth)
+	r.GET("/", func(res *phi.Response, req *phi.Request) *phi.Error {
+		token, claims = GetToken(req), GetClaims[tenantClaims](req)
+		return res.JSON("success")
+	})
+
+	exp := time.Now().Add(time.Hour).Truncate(time.Second)
+	_, tokenString, _ := tokenAuth.Encode(map[string]interface{}{
+		"jti":       "t1",
+		"sub":       "philip",
+		"iss":       "https://idp.example.com",
+		"aud":       []string{"api"},
+		"exp":       exp,
+		"scope":     "orders:read orders:write",
+		"roles":     []string{"admin"},
+		"tenant_id": "acme",
+		"seats":     5,
+	})
+
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set("Authorization", "Bearer "+tokenString)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	if token.ID != "t1" || token.Subject != "philip" || token.Issuer != "https://idp.example.com" ||
+		!reflect.DeepEqual(token.Audience, []string{"api"}) || !token.ExpiresAt.Equal(exp) {
+		t.Errorf("unexpected registered claims %+v", token)
+	}
+	if !reflect.DeepEqual(token.Scopes, []string{"orders:read", "orders:write"}) || !reflect.DeepEqual(token.Roles, []string{"admin"}) {
+		t.Errorf("unexpected scopes %v and roles %v", token.Scopes, token.Roles)
+	}
+	if token.Claims["tenant_id"] != "acme" {
+		t.Errorf("unexpected private claims %v", token.Claims)
+	}
+
+	if claims == nil || *claims != (tenantClaims{Subject: "philip", TenantID: "acme", Seats: 5}) {
+		t.Errorf("unexpected typed claims %+v", claims)
+	}
+	if GetClaims[Token](&phi.Request{Request: req}) != nil {
+		t.Error("expected no claims without token")
+	}
+
+	// claims failing to decode are unauthorized
+	_, tokenString, _ = tokenAuth.Encode(map[string]interface{}{"sub": "philip", "seats": "many"})
+	req = httptest.NewRequest("GET", "/", nil)
+	req.Header.Set("Authorization", "Bearer "+tokenString)
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("expected status 401, got %d", w.Code)
+	}
+}
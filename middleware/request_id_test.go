@@ -69,3 +69,30 @@ func TestRequestID(t *testing.T) {
 		}
 	}
 }
+
+func TestRequestIDEnvelope(t *testing.T) {
+	r := phi.NewRouter()
+	r.Use(RequestID)
+	r.GET("/", func(w *phi.Response, r *phi.Request) *phi.Error {
+		return w.JSON("ok")
+	})
+
+	serve := func() string {
+		req, _ := http.NewRequest("GET", "/", nil)
+		req.Header.Add(RequestIDHeader, "req-123456")
+
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w.Body.String()
+	}
+
+	// request ids are added to envelopes only if enabled
+	if body := serve(); body != `{"data":"ok"}` {
+		t.Fatalf("unexpected body %s", body)
+	}
+
+	r.RequestIDMeta(GetReqID)
+	if body := serve(); body != `{"data":"ok","meta":{"requestId":"req-123456"}}` {
+		t.Fatalf("unexpected body %s", body)
+	}
+}
@@ -0,0 +1,72 @@
+package middleware
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"go.philip.id/phi"
+)
+
+func TestWebSocketThroughMiddlewares(t *testing.T) {
+	r := phi.NewRouter()
+	r.Use(Logger, Compress(5), func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx := context.WithValue(r.Context(), TOKEN_CONTEXT, Token{ID: "42", Subject: "ws"})
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	})
+
+	r.WebSocket("/ws/{room}", func(c *phi.Conn) *phi.Error {
+		room, err := c.URLParam("room")
+		if err != nil {
+			return err
+		}
+
+		if e := c.WriteMessage(phi.TextMessage, []byte(room+":"+GetToken(c.Request).ID)); e != nil {
+			return phi.UnknownError(e)
+		}
+
+		return nil
+	})
+
+	ts := httptest.NewServer(r)
+	defer ts.Close()
+
+	conn, err := net.Dial("tcp", ts.Listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+
+	req, _ := http.NewRequest("GET", ts.URL+"/ws/lobby", nil)
+	req.Header.Set("Connection", "Upgrade")
+	req.Header.Set("Upgrade", "websocket")
+	req.Header.Set("Sec-WebSocket-Version", "13")
+	req.Header.Set("Sec-WebSocket-Key", "dGhlIHNhbXBsZSBub25jZQ==")
+	req.Header.Set("Accept-Encoding", "gzip")
+	req.Write(conn)
+
+	br := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(br, req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != http.StatusSwitchingProtocols {
+		t.Fatalf("unexpected status %d", resp.StatusCode)
+	}
+
+	frame := make([]byte, 2+len("lobby:42"))
+	if _, err := io.ReadFull(br, frame); err != nil {
+		t.Fatal(err)
+	}
+	if frame[0] != 0x81 || string(frame[2:]) != "lobby:42" {
+		t.Fatalf("unexpected frame %q", frame)
+	}
+}
@@ -3,6 +3,8 @@ package middleware
 import (
 	"net/http/httptest"
 	"testing"
+
+	"go.philip.id/phi"
 )
 
 func TestHttpFancyWriterRemembersWroteHeaderWhenFlushed(t *testing.T) {
@@ -22,3 +24,28 @@ func TestHttp2FancyWriterRemembersWroteHeaderWhenFlushed(t *testing.T) {
 		t.Fatal("want Flush to have set wroteHeader=true")
 	}
 }
+
+func TestWrappedWriterStreamsEvents(t *testing.T) {
+	r := phi.NewRouter()
+	r.Use(Logger, Compress(5))
+	r.GET("/", func(w *phi.Response, r *phi.Request) *phi.Error {
+		events, err := w.SSE()
+		if err != nil {
+			return err
+		}
+		defer events.Close()
+
+		if err := events.Send(phi.Event{Data: "hi"}); err != nil {
+			return phi.UnknownError(err)
+		}
+
+		return nil
+	})
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+
+	if !w.Flushed || w.Body.String() != "data: hi\n\n" {
+		t.Fatalf("unexpected response %v %q", w.Flushed, w.Body.String())
+	}
+}
@@ -0,0 +1,522 @@
+package phi
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+var (
+	fileHeaderType = reflect.TypeOf(FileHeader{})
+
+	errFileTooLarge    = errors.New("phi: file too large")
+	errRequestTooLarge = errors.New("phi: request body too large")
+)
+
+// FileHeader describes a file uploaded with a multipart form, see
+// BindMultipart.
+type FileHeader struct {
+	Filename string
+	Header   textproto.MIMEHeader
+	Size     int64
+
+	// ContentType is sniffed from the content of the file, the type sent by
+	// the client is in Header
+	ContentType string
+
+	// Key of the content in the storage, empty for files kept in memory
+	Key string
+
+	content []byte
+	storage FileStorage
+}
+
+// Open opens the content of the file.
+func (f *FileHeader) Open() (io.ReadCloser, error) {
+	if f.storage == nil {
+		return io.NopCloser(bytes.NewReader(f.content)), nil
+	}
+
+	return f.storage.Open(f.Key)
+}
+
+// Remove removes the content of the file from the storage.
+func (f *FileHeader) Remove() error {
+	if f.storage == nil {
+		f.content = nil
+		return nil
+	}
+
+	return f.storage.Remove(f.Key)
+}
+
+// FileStorage stores the content of uploaded files exceeding the memory
+// limit of BindMultipart, f.e. on disk or in an object store.
+type FileStorage interface {
+	// Store streams the content of the file into the storage and returns
+	// the key to open it. Stored content may be incomplete if reading
+	// content fails.
+	Store(ctx context.Context, f *FileHeader, content io.Reader) (key string, err error)
+
+	// Open opens the stored content.
+	Open(key string) (io.ReadCloser, error)
+
+	// Remove removes the stored content.
+	Remove(key string) error
+}
+
+// TempFileStorage stores files as temporary files in Dir, or in the default
+// directory for temporary files if empty.
+type TempFileStorage struct {
+	Dir string
+}
+
+func (s TempFileStorage) Store(ctx context.Context, f *FileHeader, content io.Reader) (string, error) {
+	file, err := os.CreateTemp(s.Dir, "phi-upload-*")
+	if err != nil {
+		return "", err
+	}
+
+	_, err = io.Copy(file, content)
+	if cerr := file.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(file.Name())
+		return "", err
+	}
+
+	return file.Name(), nil
+}
+
+func (s TempFileStorage) Open(key string) (io.ReadCloser, error) {
+	return os.Open(key)
+}
+
+func (s TempFileStorage) Remove(key string) error {
+	return os.Remove(key)
+}
+
+// MultipartOptions limit the multipart forms read by BindMultipart
+type MultipartOptions struct {
+	// MaxFileSize is the maximum size of a single file, 10MB if zero. The
+	// `maxsize` tag of a field overrides it.
+	MaxFileSize int64
+
+	// MaxTotalSize is the maximum size of the request body, 32MB if zero
+	MaxTotalSize int64
+
+	// MaxMemory is the maximum size of files kept in memory, larger files
+	// are streamed to Storage, 1MB if zero
+	MaxMemory int64
+
+	// AllowedTypes are the media types or ranges like image/* files may
+	// have, any if empty. The `accept` tag of a field overrides them.
+	AllowedTypes []string
+
+	// Storage stores files exceeding MaxMemory, TempFileStorage if nil
+	Storage FileStorage
+}
+
+// fileField is a struct field receiving uploaded files
+type fileField struct {
+	index    []int
+	multiple bool
+	maxSize  int64
+	accept   []string
+}
+
+// BindMultipart fills a struct from a multipart form, form values and
+// files are mapped by the `form` tag of the fields. Files are bound to
+// fields of type *FileHeader or []*FileHeader, the `maxsize` and `accept`
+// tags restrict them:
+//
+//	type Upload struct {
+//		Title  string            `form:"title" validate:"required"`
+//		Avatar *phi.FileHeader   `form:"avatar" maxsize:"2MB" accept:"image/png,image/jpeg" validate:"required"`
+//		Files  []*phi.FileHeader `form:"files" validate:"max=5"`
+//	}
+//
+//	upload, err := phi.BindMultipart[Upload](r, phi.MultipartOptions{MaxTotalSize: 50 << 20})
+//
+// The media types of files are sniffed from their content. Form values are
+// bound and validated like BindQuery. Files exceeding the memory limit are
+// streamed to the storage, they are removed if binding fails and have to be
+// removed with FileHeader.Remove otherwise. Files of untagged fields are
+// discarded.
+//
+// Requests exceeding the size limits result in errors with status 413,
+// files of other types in UnsupportedMediaTypeError.
+func BindMultipart[T any](r *Request, opts MultipartOptions) (*T, *Error) {
+	var data T
+
+	v := reflect.ValueOf(&data).Elem()
+	if v.Kind() != reflect.Struct {
+		return nil, &Error{
+			Error:      "bindingFailed",
+			Message:    fmt.Sprintf("cannot bind multipart form to %T", data),
+			StatusCode: http.StatusInternalServerError,
+		}
+	}
+
+	fields := map[string]*fileField{}
+	if err := collectFileFields(v.Type(), nil, fields); err != nil {
+		return nil, &Error{
+			Error:      "bindingFailed",
+			Message:    err.Error(),
+			StatusCode: http.StatusInternalServerError,
+		}
+	}
+
+	opts = opts.withDefaults()
+	body := &limitedBody{ReadCloser: r.Body, remaining: opts.MaxTotalSize}
+	r.Body = body
+
+	mr, err := r.MultipartReader()
+	if err != nil {
+		mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+		if !strings.HasPrefix(mediaType, "multipart/") {
+			return nil, UnsupportedMediaTypeError(mediaType)
+		}
+		return nil, BodyParameterError(err.Error())
+	}
+
+	values := map[string][]string{}
+	files := map[string][]*FileHeader{}
+	removeFiles := func() {
+		for _, fs := range files {
+			for _, f := range fs {
+				f.Remove()
+			}
+		}
+	}
+
+	for {
+		part, err := mr.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			removeFiles()
+			return nil, body.error("", err)
+		}
+
+		name := part.FormName()
+		field := fields[name]
+
+		switch {
+		case part.FileName() == "":
+			value, err := io.ReadAll(part)
+			if err != nil {
+				removeFiles()
+				return nil, body.error(name, err)
+			}
+			values[name] = append(values[name], string(value))
+
+		case field == nil || (!field.multiple && len(files[name]) > 0):
+			if _, err := io.Copy(io.Discard, part); err != nil {
+				removeFiles()
+				return nil, body.error(name, err)
+			}
+
+		default:
+			f, err := opts.readFile(r.Context(), part, field, body)
+			if err != nil {
+				removeFiles()
+				return nil, err
+			}
+			files[name] = append(files[name], f)
+		}
+		part.Close()
+	}
+
+	for name, field := range fields {
+		if len(files[name]) == 0 {
+			continue
+		}
+
+		fv := v.FieldByIndex(field.index)
+		if field.multiple {
+			fv.Set(reflect.ValueOf(files[name]))
+		} else {
+			fv.Set(reflect.ValueOf(files[name][0]))
+		}
+	}
+
+	errs := []fieldError{}
+	if err := bindStruct("form", v, func(key string) []string { return values[key] }, &errs); err != nil {
+		removeFiles()
+		return nil, &Error{
+			Error:      "bindingFailed",
+			Message:    err.Error(),
+			StatusCode: http.StatusInternalServerError,
+		}
+	}
+
+	if len(errs) > 0 {
+		removeFiles()
+		return nil, validationError(errs, BodyParameterError, InvalidBodyParameterError)
+	}
+
+	return &data, nil
+}
+
+// collectFileFields collects the fields of type *FileHeader and
+// []*FileHeader by their form name, including those of embedded structs
+func collectFileFields(t reflect.Type, index []int, fields map[string]*fileField) error {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name := field.Tag.Get("form")
+		idx := append(append([]int{}, index...), i)
+
+		if field.Anonymous && name == "" && field.Type.Kind() == reflect.Struct {
+			if err := collectFileFields(field.Type, idx, fields); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if !field.IsExported() || name == "" || name == "-" || bindElemType(field.Type) != fileHeaderType {
+			continue
+		}
+
+		multiple := field.Type.Kind() == reflect.Slice
+		if field.Type != reflect.PtrTo(fileHeaderType) && field.Type != reflect.SliceOf(reflect.PtrTo(fileHeaderType)) {
+			return fmt.Errorf("field %s: files have to be bound to *FileHeader or []*FileHeader", name)
+		}
+
+		f := &fileField{index: idx, multiple: multiple}
+		if size := field.Tag.Get("maxsize"); size != "" {
+			n, err := parseSize(size)
+			if err != nil {
+				return fmt.Errorf("field %s: %w", name, err)
+			}
+			f.maxSize = n
+		}
+		if accept := field.Tag.Get("accept"); accept != "" {
+			for _, mediaType := range strings.Split(accept, ",") {
+				f.accept = append(f.accept, strings.TrimSpace(mediaType))
+			}
+		}
+
+		fields[name] = f
+	}
+
+	return nil
+}
+
+// readFile reads the file part, keeping it in memory up to MaxMemory and
+// streaming it to the storage otherwise
+func (o MultipartOptions) readFile(ctx context.Context, part *multipart.Part, field *fileField, body *limitedBody) (*FileHeader, *Error) {
+	name := part.FormName()
+
+	maxSize := o.MaxFileSize
+	if field.maxSize > 0 {
+		maxSize = field.maxSize
+	}
+	content := &limitedReader{r: part, remaining: maxSize}
+
+	head := make([]byte, 512)
+	n, err := io.ReadFull(content, head)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return nil, body.error(name, err)
+	}
+	head = head[:n]
+
+	f := &FileHeader{
+		Filename:    part.FileName(),
+		Header:      part.Header,
+		ContentType: sniffContentType(head),
+	}
+
+	accept := o.AllowedTypes
+	if len(field.accept) > 0 {
+		accept = field.accept
+	}
+	if !acceptsMediaType(accept, f.ContentType) {
+		return nil, UnsupportedMediaTypeError(f.ContentType).WithDetails(FieldError{
+			Path:    name,
+			Rule:    "accept",
+			Message: "must be one of [" + strings.Join(accept, " ") + "]",
+			Value:   f.ContentType,
+		})
+	}
+
+	buf := bytes.NewBuffer(head)
+	if _, err := io.CopyN(buf, content, o.MaxMemory-int64(n)+1); err == io.EOF {
+		f.content = buf.Bytes()
+		f.Size = int64(buf.Len())
+		return f, nil
+	} else if err != nil {
+		return nil, body.error(name, err)
+	}
+
+	key, err := o.Storage.Store(ctx, f, io.MultiReader(buf, content))
+	if content.exceeded || body.tooLarge(err) {
+		if err == nil {
+			o.Storage.Remove(key)
+		}
+		return nil, body.error(name, errFileTooLarge)
+	}
+	if err != nil {
+		return nil, UnknownError(err)
+	}
+
+	f.Key = key
+	f.Size = maxSize - content.remaining
+	f.storage = o.Storage
+
+	return f, nil
+}
+
+// withDefaults returns the options with the defaults of unset limits
+func (o MultipartOptions) withDefaults() MultipartOptions {
+	if o.MaxFileSize <= 0 {
+		o.MaxFileSize = 10 << 20
+	}
+	if o.MaxTotalSize <= 0 {
+		o.MaxTotalSize = 32 << 20
+	}
+	if o.MaxMemory <= 0 {
+		o.MaxMemory = 1 << 20
+	}
+	if o.Storage == nil {
+		o.Storage = TempFileStorage{}
+	}
+
+	return o
+}
+
+// LimitBody returns the body limited to n bytes. Reading beyond the limit
+// fails with an error which Validate, Bind and BindMultipart report as
+// RequestTooLargeError, see middleware.BodyLimit.
+func LimitBody(body io.ReadCloser, n int64) io.ReadCloser {
+	return &limitedBody{ReadCloser: body, remaining: n}
+}
+
+// limitedBody fails reading the request body beyond the total size limit
+type limitedBody struct {
+	io.ReadCloser
+	remaining int64
+	exceeded  bool
+}
+
+func (b *limitedBody) Read(p []byte) (int, error) {
+	if b.exceeded {
+		return 0, errRequestTooLarge
+	}
+	if int64(len(p)) > b.remaining+1 {
+		p = p[:b.remaining+1]
+	}
+
+	n, err := b.ReadCloser.Read(p)
+	if int64(n) > b.remaining {
+		b.exceeded = true
+		return int(b.remaining), errRequestTooLarge
+	}
+	b.remaining -= int64(n)
+
+	return n, err
+}
+
+// tooLarge reports whether reading the body failed due to its size limit
+// or that of an outer LimitBody, f.e. of middleware.BodyLimit
+func (b *limitedBody) tooLarge(err error) bool {
+	return b.exceeded || errors.Is(err, errRequestTooLarge)
+}
+
+// error returns the error of failing to read the part `name`
+func (b *limitedBody) error(name string, err error) *Error {
+	switch {
+	case b.tooLarge(err):
+		return RequestTooLargeError("request body too large")
+	case errors.Is(err, errFileTooLarge):
+		return RequestTooLargeError(fmt.Sprintf("file '%s' too large", name)).WithDetails(FieldError{
+			Path:    name,
+			Rule:    "maxsize",
+			Message: "file too large",
+		})
+	}
+
+	return BodyParameterError(err.Error())
+}
+
+// limitedReader fails reading beyond the size limit of a file
+type limitedReader struct {
+	r         io.Reader
+	remaining int64
+	exceeded  bool
+}
+
+func (l *limitedReader) Read(p []byte) (int, error) {
+	if l.exceeded {
+		return 0, errFileTooLarge
+	}
+	if int64(len(p)) > l.remaining+1 {
+		p = p[:l.remaining+1]
+	}
+
+	n, err := l.r.Read(p)
+	if int64(n) > l.remaining {
+		l.exceeded = true
+		return int(l.remaining), errFileTooLarge
+	}
+	l.remaining -= int64(n)
+
+	return n, err
+}
+
+// sniffContentType returns the media type of the content without params
+func sniffContentType(head []byte) string {
+	mediaType, _, err := mime.ParseMediaType(http.DetectContentType(head))
+	if err != nil {
+		return "application/octet-stream"
+	}
+
+	return mediaType
+}
+
+// acceptsMediaType reports whether the media type is in one of the ranges,
+// any type is accepted without ranges
+func acceptsMediaType(ranges []string, mediaType string) bool {
+	if len(ranges) == 0 {
+		return true
+	}
+
+	for _, mediaRange := range ranges {
+		if matchMediaRange(mediaRange, mediaType) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// parseSize parses sizes like 512, 64KB, 2MB or 1GB
+func parseSize(size string) (int64, error) {
+	s, unit := strings.ToUpper(strings.TrimSpace(size)), int64(1)
+	for suffix, n := range map[string]int64{"KB": 1 << 10, "MB": 1 << 20, "GB": 1 << 30} {
+		if strings.HasSuffix(s, suffix) {
+			s, unit = strings.TrimSpace(strings.TrimSuffix(s, suffix)), n
+			break
+		}
+	}
+	s = strings.TrimSuffix(s, "B")
+
+	n, err := strconv.ParseInt(s, 10, 64)
+	if err != nil || n <= 0 {
+		return 0, fmt.Errorf("invalid size '%s'", size)
+	}
+
+	return n * unit, nil
+}
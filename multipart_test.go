@@ -0,0 +1,172 @@
+package phi
+
+import (
+	"bytes"
+	"io"
+	"mime/multipart"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+)
+
+type uploadForm struct {
+	Title  string        `form:"title" validate:"required"`
+	Tags   []string      `form:"tag"`
+	Avatar *FileHeader   `form:"avatar" maxsize:"1KB" accept:"image/png,image/jpeg" validate:"required"`
+	Files  []*FileHeader `form:"files" validate:"max=2"`
+}
+
+var pngHeader = "\x89PNG\r\n\x1a\n"
+
+type formPart struct {
+	name, filename, content string
+}
+
+func multipartRequest(t *testing.T, parts ...formPart) *Request {
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+
+	for _, p := range parts {
+		var w io.Writer
+		var err error
+		if p.filename == "" {
+			w, err = mw.CreateFormField(p.name)
+		} else {
+			w, err = mw.CreateFormFile(p.name, p.filename)
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		io.WriteString(w, p.content)
+	}
+	mw.Close()
+
+	req := httptest.NewRequest("POST", "/upload", &body)
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	return &Request{req}
+}
+
+func TestBindMultipart(t *testing.T) {
+	dir := t.TempDir()
+	opts := MultipartOptions{MaxMemory: 64, Storage: TempFileStorage{Dir: dir}}
+
+	large := strings.Repeat("x", 200)
+	r := multipartRequest(t,
+		formPart{"title", "", "holiday"},
+		formPart{"tag", "", "beach"},
+		formPart{"tag", "", "sea"},
+		formPart{"avatar", "me.png", pngHeader + "data"},
+		formPart{"files", "a.txt", "small"},
+		formPart{"files", "b.txt", large},
+		formPart{"unknown", "c.txt", "ignored"},
+	)
+
+	form, err := BindMultipart[uploadForm](r, opts)
+	if err != nil {
+		t.Fatalf("unexpected error %+v", err)
+	}
+
+	if form.Title != "holiday" || strings.Join(form.Tags, ",") != "beach,sea" {
+		t.Errorf("unexpected form values %+v", form)
+	}
+	if form.Avatar.Filename != "me.png" || form.Avatar.ContentType != "image/png" || form.Avatar.Key != "" {
+		t.Errorf("unexpected avatar %+v", form.Avatar)
+	}
+	if len(form.Files) != 2 || form.Files[1].Key == "" || form.Files[1].Size != 200 || form.Files[1].ContentType != "text/plain" {
+		t.Fatalf("unexpected files %+v", form.Files)
+	}
+
+	for f, expected := range map[*FileHeader]string{form.Avatar: pngHeader + "data", form.Files[1]: large} {
+		rc, err := f.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		content, _ := io.ReadAll(rc)
+		rc.Close()
+		if string(content) != expected {
+			t.Errorf("%s: unexpected content %q", f.Filename, content)
+		}
+	}
+
+	if err := form.Files[1].Remove(); err != nil {
+		t.Error(err)
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 0 {
+		t.Errorf("expected stored file to be removed, got %d files", len(entries))
+	}
+
+	tests := []struct {
+		name   string
+		req    *Request
+		opts   MultipartOptions
+		status int
+		detail string
+	}{
+		{"file size", multipartRequest(t,
+			formPart{"title", "", "x"},
+			formPart{"avatar", "me.png", pngHeader + strings.Repeat("x", 1024)},
+		), opts, 413, "avatar:maxsize"},
+		{"total size", multipartRequest(t,
+			formPart{"title", "", "x"},
+			formPart{"files", "a.txt", large},
+		), MultipartOptions{MaxTotalSize: 256, MaxMemory: 64, Storage: opts.Storage}, 413, ""},
+		{"media type", multipartRequest(t,
+			formPart{"avatar", "me.png", "GIF89a"},
+		), opts, 415, "avatar:accept"},
+		{"validation", multipartRequest(t,
+			formPart{"files", "a.txt", large},
+			formPart{"files", "b.txt", large},
+			formPart{"files", "c.txt", large},
+		), opts, 400, "title:required"},
+		{"content type", &Request{httptest.NewRequest("POST", "/upload", strings.NewReader("{}"))}, opts, 415, ""},
+	}
+
+	for _, tt := range tests {
+		_, err := BindMultipart[uploadForm](tt.req, tt.opts)
+		if err == nil || err.StatusCode != tt.status {
+			t.Errorf("%s: expected status %d, got %+v", tt.name, tt.status, err)
+			continue
+		}
+		if tt.detail != "" && (len(err.Details) == 0 || err.Details[0].Path+":"+err.Details[0].Rule != tt.detail) {
+			t.Errorf("%s: expected detail %s, got %+v", tt.name, tt.detail, err.Details)
+		}
+	}
+
+	// bodies and files exactly at their limit pass
+	exact := multipartRequest(t,
+		formPart{"title", "", "x"},
+		formPart{"avatar", "me.png", pngHeader + strings.Repeat("x", 1024-len(pngHeader))},
+	)
+	opts.MaxTotalSize = exact.ContentLength
+	form, err = BindMultipart[uploadForm](exact, opts)
+	if err != nil || form.Avatar.Size != 1024 {
+		t.Fatalf("expected body at limit to pass, got %+v", err)
+	}
+	form.Avatar.Remove()
+
+	// files of failed requests are removed
+	if entries, _ := os.ReadDir(dir); len(entries) != 0 {
+		t.Errorf("expected stored files to be removed, got %d files", len(entries))
+	}
+}
+
+func TestLimitedBody(t *testing.T) {
+	tests := []struct {
+		body    string
+		limit   int64
+		tooLong bool
+	}{
+		{"hello", 10, false},
+		{"hello", 5, false},
+		{"hello", 4, true},
+	}
+
+	for _, tt := range tests {
+		body := &limitedBody{ReadCloser: io.NopCloser(strings.NewReader(tt.body)), remaining: tt.limit}
+		data, err := io.ReadAll(body)
+		if tt.tooLong != (err == errRequestTooLarge) || (!tt.tooLong && string(data) != tt.body) {
+			t.Errorf("%q of %d: unexpected %q %v", tt.body, tt.limit, data, err)
+		}
+	}
+}
@@ -26,6 +26,10 @@ type Mux struct {
 	// The radix trie router
 	tree *node
 
+	// Guards the tree against modifications while routing, shared with
+	// inline muxes
+	mu *sync.RWMutex
+
 	// Custom method not allowed handler
 	methodNotAllowedHandler http.HandlerFunc
 
@@ -42,18 +46,67 @@ type Mux struct {
 	// The middleware stack
 	middlewares []func(http.Handler) http.Handler
 
+	// Requirements of the guards of the middleware stack, recorded when the
+	// mux handler is built, see WalkRequirements
+	requirements Requirements
+
 	// Controls the behaviour of middleware chain generation when a mux
 	// is registered as an inline group inside another mux.
 	inline bool
+
+	// Respond with RFC 7807 problem details instead of the ErrorHandler
+	problemDetails bool
+
+	// Custom error handler for errors returned by Handler's
+	errorHandler ErrorHandlerFunc
+
+	// Custom envelope of payloads written by Response.JSON, inherited from
+	// the parent router if envelopeInherited
+	envelope          Envelope
+	envelopeInherited bool
+
+	// Request id added to the meta data of envelopes, see RequestIDMeta
+	requestIDFunc func(ctx context.Context) string
+
+	// Options decoding request bodies, see Decoding
+	decodeOptions *DecodeOptions
+
+	// Patterns of the named routes of the mux, see RouteRef.Name
+	names map[string]string
+
+	// Subrouters of the hosts registered with Host
+	hosts []*hostRoute
+
+	// Request predicates of inline muxes, see Matching
+	matchers []Matcher
+
+	// Options the mux was created with, applied to its subrouters as well
+	opts []Option
+
+	// Report conflicting routes on registration, see Strict
+	strict bool
+
+	// Record routes for Validate, see CheckConflicts
+	checkConflicts bool
+
+	// Routes registered on the tree, see Validate
+	registrations []*registration
 }
 
+// Option configures a Mux, see NewMux.
+type Option func(mx *Mux)
+
 // NewMux returns a newly initialized Mux object that implements the Router
-// interface.
-func NewMux() *Mux {
-	mux := &Mux{tree: &node{}, pool: &sync.Pool{}}
+// interface. The options apply to subrouters created via Route and Host
+// as well.
+func NewMux(opts ...Option) *Mux {
+	mux := &Mux{tree: &node{}, pool: &sync.Pool{}, mu: &sync.RWMutex{}, opts: opts}
 	mux.pool.New = func() interface{} {
 		return NewRouteContext()
 	}
+	for _, opt := range opts {
+		opt(mux)
+	}
 	return mux
 }
 
@@ -70,6 +123,16 @@ func (mx *Mux) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	// Check if a routing context already exists from a parent router.
 	rctx, _ := r.Context().Value(RouteCtxKey).(*Context)
 	if rctx != nil {
+		rctx.problemDetails = mx.problemDetails
+		rctx.errorHandler = mx.errorHandler
+		// keep the envelope of a parent route unless the router has its own
+		if mx.envelope != nil && !mx.envelopeInherited {
+			rctx.envelope = mx.envelope
+		}
+		if mx.requestIDFunc != nil {
+			rctx.requestIDFunc = mx.requestIDFunc
+		}
+		rctx.decodeOptions = mx.decodeOptions
 		mx.handler.ServeHTTP(w, r)
 		return
 	}
@@ -82,6 +145,11 @@ func (mx *Mux) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	rctx.Reset()
 	rctx.Routes = mx
 	rctx.parentCtx = r.Context()
+	rctx.problemDetails = mx.problemDetails
+	rctx.errorHandler = mx.errorHandler
+	rctx.envelope = mx.envelope
+	rctx.requestIDFunc = mx.requestIDFunc
+	rctx.decodeOptions = mx.decodeOptions
 
 	// NOTE: r.WithContext() causes 2 allocations and context.WithValue() causes 1 allocation
 	r = r.WithContext(context.WithValue(r.Context(), RouteCtxKey, rctx))
@@ -115,9 +183,9 @@ func (mx *Mux) Resolve(token string, resolver func(w *Response, r *Request) (any
 
 	newMid := func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			resolve, err := resolver(&Response{ResponseWriter: w}, &Request{Request: r})
+			resolve, err := resolver(&Response{ResponseWriter: w, request: r}, &Request{Request: r})
 			if err != nil {
-				ErrorHandler(w, r, err)
+				HandleError(w, r, err)
 				return
 			}
 
@@ -131,108 +199,131 @@ func (mx *Mux) Resolve(token string, resolver func(w *Response, r *Request) (any
 
 // Handle adds the route `pattern` that matches any http method to
 // execute the `handler` http.Handler.
-func (mx *Mux) Handle(pattern string, handler http.Handler) {
+func (mx *Mux) Handle(pattern string, handler http.Handler) *RouteRef {
 	mx.handle(mALL, pattern, handler)
+	return mx.route(pattern)
 }
 
 // HandleFunc adds the route `pattern` that matches any http method to
 // execute the `handlerFn` http.HandlerFunc.
-func (mx *Mux) HandleFunc(pattern string, handlerFn http.HandlerFunc) {
+func (mx *Mux) HandleFunc(pattern string, handlerFn http.HandlerFunc) *RouteRef {
 	mx.handle(mALL, pattern, handlerFn)
+	return mx.route(pattern)
 }
 
 // Method adds the route `pattern` that matches `method` http method to
 // execute the `handler` http.Handler.
-func (mx *Mux) Method(method, pattern string, handler http.Handler) {
+func (mx *Mux) Method(method, pattern string, handler http.Handler) *RouteRef {
 	m, ok := methodMap[strings.ToUpper(method)]
 	if !ok {
 		panic(fmt.Sprintf("phi: '%s' http method is not supported.", method))
 	}
 	mx.handle(m, pattern, handler)
+	return mx.route(pattern)
 }
 
 // MethodFunc adds the route `pattern` that matches `method` http method to
 // execute the `handlerFn` http.HandlerFunc.
-func (mx *Mux) MethodFunc(method, pattern string, handlerFn http.HandlerFunc) {
-	mx.Method(method, pattern, handlerFn)
+func (mx *Mux) MethodFunc(method, pattern string, handlerFn http.HandlerFunc) *RouteRef {
+	return mx.Method(method, pattern, handlerFn)
 }
 
 // Connect adds the route `pattern` that matches a CONNECT http method to
 // execute the `handlerFn` http.HandlerFunc.
-func (mx *Mux) Connect(pattern string, handlerFn http.HandlerFunc) {
+func (mx *Mux) Connect(pattern string, handlerFn http.HandlerFunc) *RouteRef {
 	mx.handle(mCONNECT, pattern, handlerFn)
+	return mx.route(pattern)
 }
 
 // Delete adds the route `pattern` that matches a DELETE http method to
 // execute the `handlerFn` http.HandlerFunc.
-func (mx *Mux) Delete(pattern string, handlerFn http.HandlerFunc) {
+func (mx *Mux) Delete(pattern string, handlerFn http.HandlerFunc) *RouteRef {
 	mx.handle(mDELETE, pattern, handlerFn)
+	return mx.route(pattern)
 }
 
 // Get adds the route `pattern` that matches a GET http method to
 // execute the `handlerFn` http.HandlerFunc.
-func (mx *Mux) Get(pattern string, handlerFn http.HandlerFunc) {
+func (mx *Mux) Get(pattern string, handlerFn http.HandlerFunc) *RouteRef {
 	mx.handle(mGET, pattern, handlerFn)
+	return mx.route(pattern)
 }
 
 // Head adds the route `pattern` that matches a HEAD http method to
 // execute the `handlerFn` http.HandlerFunc.
-func (mx *Mux) Head(pattern string, handlerFn http.HandlerFunc) {
+func (mx *Mux) Head(pattern string, handlerFn http.HandlerFunc) *RouteRef {
 	mx.handle(mHEAD, pattern, handlerFn)
+	return mx.route(pattern)
 }
 
 // Options adds the route `pattern` that matches a OPTIONS http method to
 // execute the `handlerFn` http.HandlerFunc.
-func (mx *Mux) Options(pattern string, handlerFn http.HandlerFunc) {
+func (mx *Mux) Options(pattern string, handlerFn http.HandlerFunc) *RouteRef {
 	mx.handle(mOPTIONS, pattern, handlerFn)
+	return mx.route(pattern)
 }
 
 // Patch adds the route `pattern` that matches a PATCH http method to
 // execute the `handlerFn` http.HandlerFunc.
-func (mx *Mux) Patch(pattern string, handlerFn http.HandlerFunc) {
+func (mx *Mux) Patch(pattern string, handlerFn http.HandlerFunc) *RouteRef {
 	mx.handle(mPATCH, pattern, handlerFn)
+	return mx.route(pattern)
 }
 
 // Post adds the route `pattern` that matches a POST http method to
 // execute the `handlerFn` http.HandlerFunc.
-func (mx *Mux) Post(pattern string, handlerFn http.HandlerFunc) {
+func (mx *Mux) Post(pattern string, handlerFn http.HandlerFunc) *RouteRef {
 	mx.handle(mPOST, pattern, handlerFn)
+	return mx.route(pattern)
 }
 
 // Put adds the route `pattern` that matches a PUT http method to
 // execute the `handlerFn` http.HandlerFunc.
-func (mx *Mux) Put(pattern string, handlerFn http.HandlerFunc) {
+func (mx *Mux) Put(pattern string, handlerFn http.HandlerFunc) *RouteRef {
 	mx.handle(mPUT, pattern, handlerFn)
+	return mx.route(pattern)
 }
 
 // Trace adds the route `pattern` that matches a TRACE http method to
 // execute the `handlerFn` http.HandlerFunc.
-func (mx *Mux) Trace(pattern string, handlerFn http.HandlerFunc) {
+func (mx *Mux) Trace(pattern string, handlerFn http.HandlerFunc) *RouteRef {
 	mx.handle(mTRACE, pattern, handlerFn)
+	return mx.route(pattern)
+}
+
+// WebSocket adds the route `pattern` that upgrades GET requests to websocket
+// connections handled by fn, see WebSocketHandler for custom options.
+func (mx *Mux) WebSocket(pattern string, fn func(c *Conn) *Error) *RouteRef {
+	mx.handle(mGET, pattern, WebSocketHandler(fn, WebSocketOptions{}))
+	return mx.route(pattern)
 }
 
 // Get adds the route `pattern` that matches a GET http method to
 // execute the `handlerFn` http.HandlerFunc wrapped into errorHandler functionality.
-func (mx *Mux) GET(pattern string, handler Handler) {
+func (mx *Mux) GET(pattern string, handler Handler) *RouteRef {
 	mx.handle(mGET, pattern, Handler(handler))
+	return mx.route(pattern)
 }
 
 // Get adds the route `pattern` that matches a GET http method to
 // execute the `handlerFn` http.HandlerFunc wrapped into errorHandler functionality.
-func (mx *Mux) POST(pattern string, handler Handler) {
+func (mx *Mux) POST(pattern string, handler Handler) *RouteRef {
 	mx.handle(mPOST, pattern, Handler(handler))
+	return mx.route(pattern)
 }
 
 // Get adds the route `pattern` that matches a GET http method to
 // execute the `handlerFn` http.HandlerFunc wrapped into errorHandler functionality.
-func (mx *Mux) PUT(pattern string, handler Handler) {
+func (mx *Mux) PUT(pattern string, handler Handler) *RouteRef {
 	mx.handle(mPUT, pattern, Handler(handler))
+	return mx.route(pattern)
 }
 
 // Get adds the route `pattern` that matches a GET http method to
 // execute the `handlerFn` http.HandlerFunc wrapped into errorHandler functionality.
-func (mx *Mux) DELETE(pattern string, handler Handler) {
+func (mx *Mux) DELETE(pattern string, handler Handler) *RouteRef {
 	mx.handle(mDELETE, pattern, Handler(handler))
+	return mx.route(pattern)
 }
 
 // NotFound sets a custom http.HandlerFunc for routing paths that could
@@ -275,6 +366,71 @@ func (mx *Mux) MethodNotAllowed(handlerFn http.HandlerFunc) {
 	})
 }
 
+// ErrorHandler sets a custom ErrorHandlerFunc for errors returned by Handler's
+// of this router and its subrouters, taking precedence over the package level
+// ErrorHandler. Subrouters mounted via Route, Group or Mount inherit it unless
+// they set their own.
+func (mx *Mux) ErrorHandler(fn ErrorHandlerFunc) {
+	m := mx
+	if mx.inline && mx.parent != nil {
+		m = mx.parent
+	}
+
+	// Update the errorHandler from this point forward
+	m.errorHandler = fn
+	m.problemDetails = false
+	m.updateSubRoutes(func(subMux *Mux) {
+		if subMux.errorHandler == nil {
+			subMux.ErrorHandler(fn)
+		}
+	})
+}
+
+// ProblemDetails makes the router and its subrouters respond with RFC 7807
+// problem details, see ProblemErrorHandler. This applies to errors returned
+// by Handler's, written via HandleError, Response.Error and
+// Response.ErrorCustomStatus.
+func (mx *Mux) ProblemDetails() {
+	m := mx
+	if mx.inline && mx.parent != nil {
+		m = mx.parent
+	}
+
+	// Update the errorHandler from this point forward
+	m.errorHandler = ProblemErrorHandler
+	m.problemDetails = true
+	m.updateSubRoutes(func(subMux *Mux) {
+		if subMux.errorHandler == nil {
+			subMux.ProblemDetails()
+		}
+	})
+}
+
+// Envelope sets the envelope wrapping payloads written by Response.JSON for
+// the router and its subrouters, see DataEnvelope. Use WithEnvelope to set
+// it for single routes.
+func (mx *Mux) Envelope(e Envelope) {
+	m := mx
+	if mx.inline && mx.parent != nil {
+		m = mx.parent
+	}
+
+	m.setEnvelope(e, false)
+}
+
+// setEnvelope sets the envelope of the router and the subrouters without
+// their own
+func (mx *Mux) setEnvelope(e Envelope, inherited bool) {
+	// Update the envelope from this point forward
+	mx.envelope = e
+	mx.envelopeInherited = inherited
+	mx.updateSubRoutes(func(subMux *Mux) {
+		if subMux.envelope == nil || subMux.envelopeInherited {
+			subMux.setEnvelope(e, true)
+		}
+	})
+}
+
 // With adds inline middlewares for an endpoint handler.
 func (mx *Mux) With(middlewares ...func(http.Handler) http.Handler) Router {
 	// Similarly as in handle(), we must build the mux handler once additional
@@ -283,17 +439,20 @@ func (mx *Mux) With(middlewares ...func(http.Handler) http.Handler) Router {
 		mx.updateRouteHandler()
 	}
 
-	// Copy middlewares from parent inline muxs
+	// Copy middlewares and request predicates from parent inline muxs
 	var mws Middlewares
+	var matchers []Matcher
 	if mx.inline {
 		mws = make(Middlewares, len(mx.middlewares))
 		copy(mws, mx.middlewares)
+		matchers = append(matchers, mx.matchers...)
 	}
 	mws = append(mws, middlewares...)
 
 	im := &Mux{
-		pool: mx.pool, inline: true, parent: mx, tree: mx.tree, middlewares: mws,
+		pool: mx.pool, inline: true, parent: mx, tree: mx.tree, mu: mx.mu, middlewares: mws,
 		notFoundHandler: mx.notFoundHandler, methodNotAllowedHandler: mx.methodNotAllowedHandler,
+		matchers: matchers, opts: mx.opts,
 	}
 
 	return im
@@ -317,12 +476,97 @@ func (mx *Mux) Route(pattern string, fn func(r Router)) Router {
 	if fn == nil {
 		panic(fmt.Sprintf("phi: attempting to Route() a nil subrouter on '%s'", pattern))
 	}
-	subRouter := NewRouter()
+	subRouter := NewRouter(mx.opts...)
 	fn(subRouter)
 	mx.Mount(pattern, subRouter)
 	return subRouter
 }
 
+// Remove removes the endpoint of the `method` http method from the route
+// `pattern`, or all of its endpoints for the method "*". Of routes with
+// request predicates only the endpoints registered with the predicates of
+// the mux are removed, see Matching. It reports whether the route had such
+// an endpoint. Routes can be removed while serving requests, requests in
+// flight finish with the removed handler.
+func (mx *Mux) Remove(method, pattern string) bool {
+	m := methodTypOf(method)
+	owner := mx.variantOwner()
+
+	mx.mu.Lock()
+	defer mx.mu.Unlock()
+	if !mx.tree.UpdateRoute(m, pattern, func(ep *endpoint) bool {
+		h, ok := replaceVariant(ep.handler, owner, nil)
+		ep.handler = h
+		return ok
+	}) {
+		return false
+	}
+	if owner == nil {
+		mx.owner().unregister(m, pattern)
+	}
+	return true
+}
+
+// Replace replaces the handler of the `method` http method of the route
+// `pattern`, or all of its endpoints for the method "*", with the inline
+// middlewares and request predicates of the mux. Of routes with request
+// predicates only the endpoints registered with the predicates of the mux
+// are replaced, see Matching. It reports whether the route had such an
+// endpoint, unknown routes are not added. Routes can be replaced while
+// serving requests.
+func (mx *Mux) Replace(method, pattern string, handler http.Handler) bool {
+	m := methodTypOf(method)
+	h := mx.endpointHandler(handler)
+	owner := mx.variantOwner()
+
+	mx.mu.Lock()
+	defer mx.mu.Unlock()
+	if !mx.tree.UpdateRoute(m, pattern, func(ep *endpoint) bool {
+		nh, ok := replaceVariant(ep.handler, owner, h)
+		if !ok {
+			return false
+		}
+
+		// endpoints replaced as a whole take the param names of pattern
+		ep.handler = nh
+		if _, ok := nh.(*matchHandler); !ok {
+			ep.pattern = pattern
+			ep.paramKeys = patParamKeys(pattern)
+			ep.converters = patConverters(pattern)
+		}
+		return true
+	}) {
+		return false
+	}
+	if owner == nil {
+		mx.owner().unregister(m, pattern)
+		mx.register(m, pattern)
+	}
+	return true
+}
+
+// variantOwner returns the mux if it registers endpoints with request
+// predicates, nil otherwise, see replaceVariant
+func (mx *Mux) variantOwner() *Mux {
+	if len(mx.matchers) > 0 {
+		return mx
+	}
+	return nil
+}
+
+// methodTypOf returns the method type of `method`, "*" stands for all methods
+func methodTypOf(method string) methodTyp {
+	if method == "*" {
+		return mALL
+	}
+
+	m, ok := methodMap[strings.ToUpper(method)]
+	if !ok {
+		panic(fmt.Sprintf("phi: '%s' http method is not supported.", method))
+	}
+	return m
+}
+
 // Mount attaches another http.Handler or phi Router as a subrouter along a routing
 // path. It's very useful to split up a large API as many independent routers and
 // compose them as a single service using Mount. See _examples/.
@@ -342,12 +586,8 @@ func (mx *Mux) Mount(pattern string, handler http.Handler) {
 	}
 
 	// Assign sub-Router's with the parent not found & method not allowed handler if not specified.
-	subr, ok := handler.(*Mux)
-	if ok && subr.notFoundHandler == nil && mx.notFoundHandler != nil {
-		subr.NotFound(mx.notFoundHandler)
-	}
-	if ok && subr.methodNotAllowedHandler == nil && mx.methodNotAllowedHandler != nil {
-		subr.MethodNotAllowed(mx.methodNotAllowedHandler)
+	if subr, ok := handler.(*Mux); ok {
+		mx.inherit(subr)
 	}
 
 	mountHandler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -384,9 +624,17 @@ func (mx *Mux) Mount(pattern string, handler http.Handler) {
 }
 
 // Routes returns a slice of routing information from the tree,
-// useful for traversing available routes of a router.
+// useful for traversing available routes of a router. Subrouters of
+// hosts follow the routes of the tree, see Route.Host.
 func (mx *Mux) Routes() []Route {
-	return mx.tree.routes()
+	mx.mu.RLock()
+	routes := mx.tree.routes()
+	mx.mu.RUnlock()
+
+	if len(mx.hosts) > 0 {
+		return append(routes, mx.hostRoutes()...)
+	}
+	return routes
 }
 
 // Middlewares returns a slice of middleware handler functions.
@@ -406,7 +654,9 @@ func (mx *Mux) Match(rctx *Context, method, path string) bool {
 		return false
 	}
 
+	mx.mu.RLock()
 	node, _, h := mx.tree.FindRoute(rctx, m, path)
+	mx.mu.RUnlock()
 
 	if node != nil && node.subroutes != nil {
 		rctx.RoutePath = mx.nextRoutePath(rctx)
@@ -447,6 +697,20 @@ func (mx *Mux) handle(method methodTyp, pattern string, handler http.Handler) *n
 	}
 
 	// Build endpoint handler with inline middlewares for the route
+	h := mx.endpointHandler(handler)
+
+	// Add the endpoint to the tree and return the node
+	mx.mu.Lock()
+	defer mx.mu.Unlock()
+	if method&mSTUB == 0 {
+		mx.register(method, pattern)
+	}
+	return mx.tree.InsertRoute(method, pattern, h)
+}
+
+// endpointHandler wraps the handler of a route with the inline middlewares
+// and request predicates of the mux.
+func (mx *Mux) endpointHandler(handler http.Handler) http.Handler {
 	var h http.Handler
 	if mx.inline {
 		mx.handler = http.HandlerFunc(mx.routeHTTP)
@@ -455,8 +719,17 @@ func (mx *Mux) handle(method methodTyp, pattern string, handler http.Handler) *n
 		h = handler
 	}
 
-	// Add the endpoint to the tree and return the node
-	return mx.tree.InsertRoute(method, pattern, h)
+	// Only serve the endpoint to requests matching the predicates, these are
+	// evaluated before inline middlewares
+	if len(mx.matchers) > 0 {
+		m := mx
+		for m.inline && m.parent != nil {
+			m = m.parent
+		}
+		h = &matchHandler{routes: []matchRoute{{matchers: mx.matchers, handler: h, owner: mx}}, mux: m}
+	}
+
+	return h
 }
 
 // routeHTTP routes a http.Request through the Mux routing tree to serve
@@ -465,6 +738,14 @@ func (mx *Mux) routeHTTP(w http.ResponseWriter, r *http.Request) {
 	// Grab the route context object
 	rctx := r.Context().Value(RouteCtxKey).(*Context)
 
+	// Hand the request over to the subrouter of a matching host
+	if len(mx.hosts) > 0 {
+		if hm := mx.findHost(rctx, r.Host); hm != nil {
+			hm.ServeHTTP(w, r)
+			return
+		}
+	}
+
 	// The request routing path
 	routePath := rctx.RoutePath
 	if routePath == "" {
@@ -489,7 +770,10 @@ func (mx *Mux) routeHTTP(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Find the route
-	if _, _, h := mx.tree.FindRoute(rctx, method, routePath); h != nil {
+	mx.mu.RLock()
+	_, _, h := mx.tree.FindRoute(rctx, method, routePath)
+	mx.mu.RUnlock()
+	if h != nil {
 		h.ServeHTTP(w, r)
 		return
 	}
@@ -509,9 +793,43 @@ func (mx *Mux) nextRoutePath(rctx *Context) string {
 	return routePath
 }
 
+// inherit assigns the handlers and settings of the mux to a subrouter that
+// doesn't specify its own.
+func (mx *Mux) inherit(subr *Mux) {
+	if subr.notFoundHandler == nil && mx.notFoundHandler != nil {
+		subr.NotFound(mx.notFoundHandler)
+	}
+	if subr.methodNotAllowedHandler == nil && mx.methodNotAllowedHandler != nil {
+		subr.MethodNotAllowed(mx.methodNotAllowedHandler)
+	}
+	if subr.errorHandler == nil && mx.problemDetails {
+		subr.ProblemDetails()
+	} else if subr.errorHandler == nil && mx.errorHandler != nil {
+		subr.ErrorHandler(mx.errorHandler)
+	}
+	if subr.envelope == nil && mx.envelope != nil {
+		subr.setEnvelope(mx.envelope, true)
+	}
+	if subr.requestIDFunc == nil && mx.requestIDFunc != nil {
+		subr.RequestIDMeta(mx.requestIDFunc)
+	}
+	if subr.decodeOptions == nil && mx.decodeOptions != nil {
+		subr.Decoding(*mx.decodeOptions)
+	}
+}
+
+// owner returns the mux owning the tree of inline muxes
+func (mx *Mux) owner() *Mux {
+	m := mx
+	for m.inline && m.parent != nil {
+		m = m.parent
+	}
+	return m
+}
+
 // Recursively update data on phild routers.
 func (mx *Mux) updateSubRoutes(fn func(subMux *Mux)) {
-	for _, r := range mx.tree.routes() {
+	for _, r := range mx.Routes() {
 		subMux, ok := r.SubRoutes.(*Mux)
 		if !ok {
 			continue
@@ -525,7 +843,7 @@ func (mx *Mux) updateSubRoutes(fn func(subMux *Mux)) {
 // point, no other middlewares can be registered on this Mux's stack. But you can still
 // compose additional middlewares via Group()'s or using a chained middleware handler.
 func (mx *Mux) updateRouteHandler() {
-	mx.handler = chain(mx.middlewares, http.HandlerFunc(mx.routeHTTP))
+	mx.handler, mx.requirements = chain(mx.middlewares, http.HandlerFunc(mx.routeHTTP))
 }
 
 // methodNotAllowedHandler is a helper function to respond with a 405,
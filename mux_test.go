@@ -1694,6 +1694,97 @@ func TestMuxMatch(t *testing.T) {
 	}
 }
 
+func TestMuxRemoveReplace(t *testing.T) {
+	text := func(s string) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(s))
+		}
+	}
+
+	r := NewRouter()
+	r.Get("/users", text("users"))
+	r.Post("/users", text("create user"))
+	r.Get("/uploads", text("uploads"))
+	r.With(func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte("mw "))
+			next.ServeHTTP(w, r)
+		})
+	}).Handle("/plugins/{name}", text("plugin"))
+
+	ts := httptest.NewServer(r)
+	defer ts.Close()
+
+	// routes are changed while serving requests
+	var wg sync.WaitGroup
+	stop := make(chan struct{})
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-stop:
+					return
+				default:
+					testRequest(t, ts, "GET", "/users", nil)
+				}
+			}
+		}()
+	}
+
+	if !r.Remove("GET", "/uploads") {
+		t.Error("expected GET /uploads to be removed")
+	}
+	if r.Remove("GET", "/uploads") || r.Remove("DELETE", "/users") {
+		t.Error("expected unknown routes not to be removed")
+	}
+	if !r.Replace("GET", "/users", text("new users")) {
+		t.Error("expected GET /users to be replaced")
+	}
+	if r.Replace("GET", "/missing", text("missing")) {
+		t.Error("expected unknown routes not to be added")
+	}
+	if !r.Remove("PUT", "/plugins/{plugin}") {
+		t.Error("expected PUT /plugins/{name} to be removed")
+	}
+	r.With(func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte("mw2 "))
+			next.ServeHTTP(w, r)
+		})
+	}).(*Mux).Replace("POST", "/plugins/{name}", text("replaced plugin"))
+
+	close(stop)
+	wg.Wait()
+
+	tests := []struct {
+		method string
+		path   string
+		status int
+		body   string
+	}{
+		{"GET", "/users", 200, "new users"},
+		{"POST", "/users", 200, "create user"},
+		{"GET", "/uploads", 404, "404 page not found\n"},
+		{"GET", "/plugins/a", 200, "mw plugin"},
+		{"PUT", "/plugins/a", 405, ""},
+		{"POST", "/plugins/a", 200, "mw2 replaced plugin"},
+	}
+	for _, tt := range tests {
+		if resp, body := testRequest(t, ts, tt.method, tt.path, nil); resp.StatusCode != tt.status || body != tt.body {
+			t.Errorf("%s %s: expected %d %q, got %d %q", tt.method, tt.path, tt.status, tt.body, resp.StatusCode, body)
+		}
+	}
+
+	if !r.Remove("*", "/plugins/{name}") {
+		t.Error("expected /plugins/{name} to be removed")
+	}
+	if len(r.Routes()) != 1 || r.Routes()[0].Pattern != "/users" {
+		t.Errorf("unexpected routes %v", r.Routes())
+	}
+}
+
 func TestServerBaseContext(t *testing.T) {
 	r := NewRouter()
 	r.Get("/", func(w http.ResponseWriter, r *http.Request) {
@@ -1808,4 +1899,20 @@ func BenchmarkMux(b *testing.B) {
 			}
 		})
 	}
+
+	// routes are looked up under a read lock, see Mux.Remove
+	b.Run("parallel", func(b *testing.B) {
+		b.ReportAllocs()
+		b.RunParallel(func(pb *testing.PB) {
+			w := httptest.NewRecorder()
+			reqs := make([]*http.Request, len(routes))
+			for i, path := range routes {
+				reqs[i], _ = http.NewRequest("GET", path, nil)
+			}
+
+			for i := 0; pb.Next(); i++ {
+				mx.ServeHTTP(w, reqs[i%len(reqs)])
+			}
+		})
+	})
 }
@@ -0,0 +1,178 @@
+package phi
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Converter parses URL params of typed placeholders like {id:int} once the
+// route matched, see RegisterConverter.
+type Converter struct {
+	// Regexp the param has to match, without anchors and without '/'
+	Regexp string
+
+	// Convert parses the matched param, routes whose params fail to convert
+	// don't match the request
+	Convert func(value string) (interface{}, error)
+}
+
+var converters = struct {
+	sync.RWMutex
+	byName map[string]Converter
+}{
+	byName: map[string]Converter{
+		"int": {Regexp: `-?[0-9]+`, Convert: func(value string) (interface{}, error) {
+			return strconv.Atoi(value)
+		}},
+		"uuid": {Regexp: `[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}`, Convert: func(value string) (interface{}, error) {
+			return strings.ToLower(value), nil
+		}},
+		"slug": {Regexp: `[a-z0-9]+(?:-[a-z0-9]+)*`, Convert: func(value string) (interface{}, error) {
+			return value, nil
+		}},
+		"date": {Regexp: `[0-9]{4}-[0-9]{2}-[0-9]{2}`, Convert: func(value string) (interface{}, error) {
+			return time.Parse("2006-01-02", value)
+		}},
+	},
+}
+
+// RegisterConverter adds the converter for typed placeholders `{key:name}`,
+// replacing converters of the same name. Built-in converters are:
+//
+//	int   -> int
+//	uuid  -> string, lower case
+//	slug  -> string of lower case letters, digits and dashes
+//	date  -> time.Time of a "2006-01-02" date
+//
+// Converters have to be registered before the routes using them, their
+// names take precedence over regexps of the same text.
+func RegisterConverter(name string, c Converter) {
+	if c.Convert == nil {
+		panic(fmt.Sprintf("phi: converter '%s' without Convert function", name))
+	}
+
+	converters.Lock()
+	defer converters.Unlock()
+	converters.byName[name] = c
+}
+
+// LookupConverter returns the converter registered as `name`.
+func LookupConverter(name string) (Converter, bool) {
+	converters.RLock()
+	defer converters.RUnlock()
+	c, ok := converters.byName[name]
+	return c, ok
+}
+
+// patConverters returns the converters of the params of the pattern, or nil
+// if it has no typed params
+func patConverters(pattern string) []*Converter {
+	var cs []*Converter
+	typed := false
+
+	for pattern != "" {
+		typ, _, _, _, ps, pe := patNextSegment(pattern)
+		if typ == ntStatic {
+			break
+		}
+
+		var conv *Converter
+		if typ == ntRegexp {
+			key := pattern[ps+1 : pe-1]
+			if c, ok := LookupConverter(key[strings.IndexByte(key, ':')+1:]); ok {
+				conv, typed = &c, true
+			}
+		}
+		cs = append(cs, conv)
+		pattern = pattern[pe:]
+	}
+
+	if !typed {
+		return nil
+	}
+	return cs
+}
+
+// typedParam is the converted value of a URL param
+type typedParam struct {
+	key   string
+	value interface{}
+}
+
+// convert converts the typed params of the route matched by the routing
+// context, it reports false if a param fails to convert
+func (e *endpoint) convert(rctx *Context) bool {
+	rctx.routeTypedParams = rctx.routeTypedParams[:0]
+	if e.converters == nil {
+		return true
+	}
+
+	values := rctx.routeParams.Values
+	if len(values) < len(e.converters) {
+		return false
+	}
+	values = values[len(values)-len(e.converters):]
+
+	for i, c := range e.converters {
+		if c == nil {
+			continue
+		}
+
+		v, err := c.Convert(values[i])
+		if err != nil {
+			return false
+		}
+		rctx.routeTypedParams = append(rctx.routeTypedParams, typedParam{key: e.paramKeys[i], value: v})
+	}
+
+	return true
+}
+
+// Param returns the converted value of the typed URL param `key`, f.e. of
+// the placeholder {id:int}:
+//
+//	r.GET("/users/{id:int}", func(w *phi.Response, r *phi.Request) *phi.Error {
+//		id, err := phi.Param[int](r, "id")
+//		if err != nil {
+//			return err
+//		}
+//		...
+//	})
+//
+// Untyped params are returned as string, a URLParameterError is returned if
+// the param is missing or of another type.
+func Param[T any](r *Request, key string) (T, *Error) {
+	var zero T
+
+	rctx := RouteContext(r.Context())
+	if rctx == nil {
+		return zero, URLParameterError(key)
+	}
+
+	for i := len(rctx.typedParams) - 1; i >= 0; i-- {
+		if rctx.typedParams[i].key != key {
+			continue
+		}
+
+		if v, ok := rctx.typedParams[i].value.(T); ok {
+			return v, nil
+		}
+		return zero, URLParameterError(fmt.Sprintf("parameter '%s' is of type %T", key, rctx.typedParams[i].value))
+	}
+
+	for k := len(rctx.URLParams.Keys) - 1; k >= 0; k-- {
+		if rctx.URLParams.Keys[k] != key {
+			continue
+		}
+
+		if v, ok := interface{}(rctx.URLParams.Values[k]).(T); ok {
+			return v, nil
+		}
+		return zero, URLParameterError(fmt.Sprintf("parameter '%s' is of type string", key))
+	}
+
+	return zero, URLParameterError(key)
+}
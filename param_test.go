@@ -0,0 +1,94 @@
+package phi
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+type hexColor [3]byte
+
+func TestParam(t *testing.T) {
+	RegisterConverter("color", Converter{Regexp: `[0-9a-f]{6}`, Convert: func(value string) (interface{}, error) {
+		var c hexColor
+		_, err := fmt.Sscanf(value, "%02x%02x%02x", &c[0], &c[1], &c[2])
+		return c, err
+	}})
+
+	r := NewRouter()
+	r.GET("/users/{id:int}", func(w *Response, r *Request) *Error {
+		id, err := Param[int](r, "id")
+		if err != nil {
+			return err
+		}
+		return w.Response([]byte(fmt.Sprintf("user %d", id+1)), "text/plain")
+	})
+	r.GET("/users/{name}", func(w *Response, r *Request) *Error {
+		name, err := Param[string](r, "name")
+		if err != nil {
+			return err
+		}
+		return w.Response([]byte("user "+name), "text/plain")
+	})
+	r.Route("/orders/{id:uuid}", func(r Router) {
+		r.GET("/{day:date}", func(w *Response, r *Request) *Error {
+			id, _ := Param[string](r, "id")
+			day, err := Param[time.Time](r, "day")
+			if err != nil {
+				return err
+			}
+			return w.Response([]byte(id+" "+day.Weekday().String()), "text/plain")
+		})
+	})
+	r.GET("/posts/{slug:slug}", func(w *Response, r *Request) *Error {
+		if _, err := Param[int](r, "slug"); err == nil {
+			return &Error{Error: "unexpected", StatusCode: 500}
+		}
+		if _, err := Param[int](r, "missing"); err == nil {
+			return &Error{Error: "unexpected", StatusCode: 500}
+		}
+		slug, _ := Param[string](r, "slug")
+		return w.Response([]byte(slug), "text/plain")
+	})
+	r.GET("/colors/{c:color}", func(w *Response, r *Request) *Error {
+		c, err := Param[hexColor](r, "c")
+		if err != nil {
+			return err
+		}
+		return w.Response([]byte(fmt.Sprint(c[0], c[1], c[2])), "text/plain")
+	})
+
+	tests := []struct {
+		path   string
+		status int
+		body   string
+	}{
+		{"/users/41", 200, "user 42"},
+		{"/users/-1", 200, "user 0"},
+		{"/users/joe", 200, "user joe"},
+		// overflowing ints fail to convert and fall through to the next route
+		{"/users/99999999999999999999", 200, "user 99999999999999999999"},
+		{"/orders/6BA7B810-9DAD-11D1-80B4-00C04FD430C8/2024-05-12", 200, "6ba7b810-9dad-11d1-80b4-00c04fd430c8 Sunday"},
+		{"/orders/6ba7b810/2024-05-12", 404, ""},
+		{"/orders/6ba7b810-9dad-11d1-80b4-00c04fd430c8/2024-13-45", 404, ""},
+		{"/posts/hello-world-2", 200, "hello-world-2"},
+		{"/posts/Hello", 404, ""},
+		{"/colors/ff8000", 200, "255 128 0"},
+	}
+
+	for _, tt := range tests {
+		resp, body := testHandler(t, r, "GET", tt.path, nil)
+		if resp.StatusCode != tt.status || (tt.body != "" && body != tt.body) {
+			t.Errorf("%s: expected %d %q, got %d %q", tt.path, tt.status, tt.body, resp.StatusCode, body)
+		}
+	}
+
+	if u, err := r.URL("missing"); err == nil {
+		t.Errorf("unexpected url %s", u)
+	}
+	r.GET("/invoices/{id:int}", func(w *Response, r *Request) *Error { return nil }).Name("invoice")
+	if _, err := r.URL("invoice", "id", "x"); err == nil || !strings.Contains(err.Error(), "does not match") {
+		t.Errorf("expected typed param to be checked, got %v", err)
+	}
+}
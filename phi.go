@@ -56,12 +56,14 @@
 package phi
 
 import (
+	"context"
 	"net/http"
 )
 
-// NewRouter returns a new Mux object that implements the Router interface.
-func NewRouter() *Mux {
-	return NewMux()
+// NewRouter returns a new Mux object that implements the Router interface,
+// see NewMux for the options.
+func NewRouter(opts ...Option) *Mux {
+	return NewMux(opts...)
 }
 
 // Router consisting of the core routing methods used by phi's Mux,
@@ -79,6 +81,10 @@ type Router interface {
 	// With adds inline middlewares for an endpoint handler.
 	With(middlewares ...func(http.Handler) http.Handler) Router
 
+	// Matching adds inline request predicates for endpoint handlers,
+	// f.e. phi.Header, phi.Query, phi.ContentType or phi.Accept.
+	Matching(matchers ...Matcher) Router
+
 	// Group adds a new inline-Router along the current routing
 	// path, with a fresh middleware stack for the inline-Router.
 	Group(fn func(r Router)) Router
@@ -86,35 +92,40 @@ type Router interface {
 	// Route mounts a sub-Router along a `pattern`` string.
 	Route(pattern string, fn func(r Router)) Router
 
+	// Host mounts a sub-Router serving the requests of hosts matching
+	// the `pattern` string, f.e. "{tenant}.api.example.com".
+	Host(pattern string, fn func(r Router)) Router
+
 	// Mount attaches another http.Handler along ./pattern/*
 	Mount(pattern string, h http.Handler)
 
 	// Handle and HandleFunc adds routes for `pattern` that matches
-	// all HTTP methods.
-	Handle(pattern string, h http.Handler)
-	HandleFunc(pattern string, h http.HandlerFunc)
+	// all HTTP methods. Like all route registrars they return a *RouteRef
+	// to name the route for Mux.URL.
+	Handle(pattern string, h http.Handler) *RouteRef
+	HandleFunc(pattern string, h http.HandlerFunc) *RouteRef
 
 	// Method and MethodFunc adds routes for `pattern` that matches
 	// the `method` HTTP method.
-	Method(method, pattern string, h http.Handler)
-	MethodFunc(method, pattern string, h http.HandlerFunc)
+	Method(method, pattern string, h http.Handler) *RouteRef
+	MethodFunc(method, pattern string, h http.HandlerFunc) *RouteRef
 
 	// HTTP-method routing along `pattern`
-	Connect(pattern string, h http.HandlerFunc)
-	Delete(pattern string, h http.HandlerFunc)
-	Get(pattern string, h http.HandlerFunc)
-	Head(pattern string, h http.HandlerFunc)
-	Options(pattern string, h http.HandlerFunc)
-	Patch(pattern string, h http.HandlerFunc)
-	Post(pattern string, h http.HandlerFunc)
-	Put(pattern string, h http.HandlerFunc)
-	Trace(pattern string, h http.HandlerFunc)
+	Connect(pattern string, h http.HandlerFunc) *RouteRef
+	Delete(pattern string, h http.HandlerFunc) *RouteRef
+	Get(pattern string, h http.HandlerFunc) *RouteRef
+	Head(pattern string, h http.HandlerFunc) *RouteRef
+	Options(pattern string, h http.HandlerFunc) *RouteRef
+	Patch(pattern string, h http.HandlerFunc) *RouteRef
+	Post(pattern string, h http.HandlerFunc) *RouteRef
+	Put(pattern string, h http.HandlerFunc) *RouteRef
+	Trace(pattern string, h http.HandlerFunc) *RouteRef
 
 	// HTTP-method with error handling functionality
-	GET(pattern string, h Handler)
-	POST(pattern string, h Handler)
-	PUT(pattern string, h Handler)
-	DELETE(pattern string, h Handler)
+	GET(pattern string, h Handler) *RouteRef
+	POST(pattern string, h Handler) *RouteRef
+	PUT(pattern string, h Handler) *RouteRef
+	DELETE(pattern string, h Handler) *RouteRef
 
 	// NotFound defines a handler to respond whenever a route could
 	// not be found.
@@ -123,10 +134,33 @@ type Router interface {
 	// MethodNotAllowed defines a handler to respond whenever a method is
 	// not allowed.
 	MethodNotAllowed(h http.HandlerFunc)
+
+	// ErrorHandler defines a handler to respond whenever a Handler
+	// returns an error.
+	ErrorHandler(fn ErrorHandlerFunc)
+
+	// ProblemDetails makes the router respond with RFC 7807 problem details
+	// on errors.
+	ProblemDetails()
+
+	// Envelope sets the envelope wrapping payloads of Response.JSON.
+	Envelope(e Envelope)
+
+	// RequestIDMeta adds the id returned by fn to the meta data of
+	// envelopes.
+	RequestIDMeta(fn func(ctx context.Context) string)
+
+	// Decoding sets the options decoding request bodies with Validate and
+	// Bind.
+	Decoding(o DecodeOptions)
+
+	// WebSocket adds the route `pattern` upgrading GET requests to
+	// websocket connections handled by fn.
+	WebSocket(pattern string, fn func(c *Conn) *Error) *RouteRef
 }
 
 // Routes interface adds two methods for router traversal, which is also
-// used by the `docgen` subpackage to generation documentation for Routers.
+// used by the `docgen` subpackage to generate documentation for Routers.
 type Routes interface {
 	// Routes returns the routing tree in an easily traversable structure.
 	Routes() []Route
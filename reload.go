@@ -0,0 +1,125 @@
+package phi
+
+import (
+	"fmt"
+	"net/http"
+	"sync/atomic"
+)
+
+var _ Routes = &Reloadable{}
+
+// Reloadable is a http.Handler serving requests with a routing table that
+// can be replaced at runtime, f.e. to reload feature flagged endpoints or
+// tenant plugins without restarting the server:
+//
+//	rl := phi.NewReloadable(buildRoutes(cfg))
+//	go http.ListenAndServe(":3333", rl)
+//
+//	// on configuration changes
+//	rl.Reload(func(r phi.Router) {
+//		r.Use(middleware.Logger)
+//		r.Get("/", index)
+//	})
+//
+// New routers are built off to the side and published atomically, requests
+// in flight finish on the router they started with.
+type Reloadable struct {
+	active atomic.Value
+}
+
+// NewReloadable returns a Reloadable serving requests with mx.
+func NewReloadable(mx *Mux) *Reloadable {
+	rl := &Reloadable{}
+	rl.Swap(mx)
+	return rl
+}
+
+// Mux returns the active router.
+func (rl *Reloadable) Mux() *Mux {
+	mx, _ := rl.active.Load().(*Mux)
+	return mx
+}
+
+// Swap publishes mx as the active router and returns the previous one.
+func (rl *Reloadable) Swap(mx *Mux) *Mux {
+	if mx == nil {
+		panic("phi: attempting to Swap() a nil router")
+	}
+
+	old, _ := rl.active.Swap(mx).(*Mux)
+	return old
+}
+
+// Reload builds a new router with fn and the options of the active router
+// and publishes it, see Swap.
+func (rl *Reloadable) Reload(fn func(r Router)) *Mux {
+	var opts []Option
+	if active := rl.Mux(); active != nil {
+		opts = active.opts
+	}
+
+	mx := NewRouter(opts...)
+	fn(mx)
+	return rl.Swap(mx)
+}
+
+// ServeHTTP serves the request with the active router.
+func (rl *Reloadable) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	mx := rl.Mux()
+	if mx == nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	mx.ServeHTTP(w, r)
+}
+
+// Routes returns the routes of the active router.
+func (rl *Reloadable) Routes() []Route {
+	mx := rl.Mux()
+	if mx == nil {
+		return nil
+	}
+
+	return mx.Routes()
+}
+
+// Middlewares returns the middlewares of the active router.
+func (rl *Reloadable) Middlewares() Middlewares {
+	mx := rl.Mux()
+	if mx == nil {
+		return nil
+	}
+
+	return mx.Middlewares()
+}
+
+// Match searches the routes of the active router, see Mux.Match.
+func (rl *Reloadable) Match(rctx *Context, method, path string) bool {
+	mx := rl.Mux()
+	if mx == nil {
+		return false
+	}
+
+	return mx.Match(rctx, method, path)
+}
+
+// URL builds the path of a named route of the active router, see Mux.URL.
+func (rl *Reloadable) URL(name string, params ...string) (string, error) {
+	mx := rl.Mux()
+	if mx == nil {
+		return "", fmt.Errorf("phi: unknown route name '%s'", name)
+	}
+
+	return mx.URL(name, params...)
+}
+
+// findName looks up named routes of the active router when mounted
+func (rl *Reloadable) findName(name string) (string, bool) {
+	mx := rl.Mux()
+	if mx == nil {
+		return "", false
+	}
+
+	return mx.findName(name)
+}
@@ -0,0 +1,129 @@
+package phi
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+)
+
+func TestReloadable(t *testing.T) {
+	started, release := make(chan struct{}), make(chan struct{})
+
+	v1 := NewRouter()
+	v1.Get("/", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("v1"))
+	})
+	v1.Get("/slow", func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		<-release
+		w.Write([]byte("slow v1"))
+	})
+
+	rl := NewReloadable(v1)
+	ts := httptest.NewServer(rl)
+	defer ts.Close()
+
+	if _, body := testRequest(t, ts, "GET", "/", nil); body != "v1" {
+		t.Fatalf("unexpected body %q", body)
+	}
+
+	// a request in flight finishes on the previous router
+	var wg sync.WaitGroup
+	var slowBody string
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		_, slowBody = testRequest(t, ts, "GET", "/slow", nil)
+	}()
+	<-started
+
+	old := rl.Reload(func(r Router) {
+		r.Get("/", func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte("v2"))
+		})
+		r.Get("/flag", func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte("flag"))
+		})
+	})
+	if old != v1 {
+		t.Error("expected Reload to return the previous router")
+	}
+
+	if _, body := testRequest(t, ts, "GET", "/", nil); body != "v2" {
+		t.Errorf("unexpected body %q", body)
+	}
+	if resp, _ := testRequest(t, ts, "GET", "/slow", nil); resp.StatusCode != 404 {
+		t.Errorf("expected 404 for removed route, got %d", resp.StatusCode)
+	}
+
+	close(release)
+	wg.Wait()
+	if slowBody != "slow v1" {
+		t.Errorf("unexpected body of request in flight %q", slowBody)
+	}
+
+	var routes []string
+	Walk(rl, func(method string, route string, handler http.Handler, middlewares ...func(http.Handler) http.Handler) error {
+		routes = append(routes, route)
+		return nil
+	})
+	if len(routes) != 2 || routes[0] != "/" || routes[1] != "/flag" {
+		t.Errorf("expected routes of the active router, got %v", routes)
+	}
+}
+
+func TestReloadableMount(t *testing.T) {
+	api := NewRouter()
+	api.Get("/ping", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("pong"))
+	}).Name("ping")
+	rl := NewReloadable(api)
+
+	r := NewRouter()
+	r.Mount("/api", rl)
+
+	if _, body := testHandler(t, r, "GET", "/api/ping", nil); body != "pong" {
+		t.Errorf("unexpected body %q", body)
+	}
+
+	rl.Reload(func(r Router) {
+		r.Get("/pong", func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte("ping"))
+		}).Name("ping")
+	})
+
+	if _, body := testHandler(t, r, "GET", "/api/pong", nil); body != "ping" {
+		t.Errorf("unexpected body %q", body)
+	}
+	if u, _ := r.URL("ping"); u != "/api/pong" {
+		t.Errorf("unexpected url %q", u)
+	}
+}
+
+func TestReloadableZero(t *testing.T) {
+	rl := &Reloadable{}
+
+	if routes := rl.Routes(); routes != nil {
+		t.Errorf("unexpected routes %v", routes)
+	}
+	if mws := rl.Middlewares(); mws != nil {
+		t.Errorf("unexpected middlewares %v", mws)
+	}
+	if rl.Match(NewRouteContext(), "GET", "/") {
+		t.Error("expected no match")
+	}
+	if _, err := rl.URL("ping"); err == nil {
+		t.Error("expected error")
+	}
+
+	r := NewRouter()
+	r.Mount("/api", rl)
+
+	if resp, _ := testHandler(t, r, "GET", "/api/ping", nil); resp.StatusCode != 404 {
+		t.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	if _, err := r.URL("ping"); err == nil {
+		t.Error("expected error")
+	}
+}
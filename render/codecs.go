@@ -0,0 +1,125 @@
+package render
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+
+	"github.com/fxamacker/cbor/v2"
+	"github.com/vmihailenco/msgpack/v5"
+	"google.golang.org/protobuf/proto"
+	"gopkg.in/yaml.v3"
+)
+
+var errNoMessage = errors.New("render: value is not a proto.Message")
+
+type msgpackCodec struct{}
+
+func (msgpackCodec) Encode(w io.Writer, v interface{}) error {
+	enc := msgpack.NewEncoder(w)
+	enc.SetCustomStructTag("json")
+
+	return enc.Encode(v)
+}
+
+func (msgpackCodec) Decode(r io.Reader, v interface{}) error {
+	dec := msgpack.NewDecoder(r)
+	dec.SetCustomStructTag("json")
+
+	return dec.Decode(v)
+}
+
+type cborCodec struct{}
+
+// cbor falls back to json tags for struct fields without cbor tag
+func (cborCodec) Encode(w io.Writer, v interface{}) error {
+	return cbor.NewEncoder(w).Encode(v)
+}
+
+func (cborCodec) Decode(r io.Reader, v interface{}) error {
+	return cbor.NewDecoder(r).Decode(v)
+}
+
+// yamlCodec converts from and to json to honour json tags and custom json
+// marshalers of the values
+type yamlCodec struct{}
+
+func (yamlCodec) Encode(w io.Writer, v interface{}) error {
+	js, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+
+	// json is valid yaml, parse it into a node to keep the order of keys
+	var node yaml.Node
+	if err := yaml.Unmarshal(js, &node); err != nil {
+		return err
+	}
+	blockStyle(&node)
+
+	enc := yaml.NewEncoder(w)
+	enc.SetIndent(2)
+	if err := enc.Encode(&node); err != nil {
+		return err
+	}
+
+	return enc.Close()
+}
+
+func (yamlCodec) Decode(r io.Reader, v interface{}) error {
+	var data interface{}
+	if err := yaml.NewDecoder(r).Decode(&data); err != nil {
+		return err
+	}
+
+	js, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+
+	return json.Unmarshal(js, v)
+}
+
+// blockStyle resets the flow style and quoting of nodes parsed from json
+func blockStyle(node *yaml.Node) {
+	node.Style = 0
+	for _, n := range node.Content {
+		blockStyle(n)
+	}
+}
+
+type protobufCodec struct{}
+
+func (protobufCodec) Supports(v interface{}) bool {
+	_, ok := v.(proto.Message)
+	return ok
+}
+
+func (protobufCodec) Encode(w io.Writer, v interface{}) error {
+	m, ok := v.(proto.Message)
+	if !ok {
+		return errNoMessage
+	}
+
+	b, err := proto.Marshal(m)
+	if err != nil {
+		return err
+	}
+
+	_, err = w.Write(b)
+	return err
+}
+
+func (protobufCodec) Decode(r io.Reader, v interface{}) error {
+	m, ok := v.(proto.Message)
+	if !ok {
+		return errNoMessage
+	}
+
+	b, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+
+	return proto.Unmarshal(b, m)
+}
@@ -0,0 +1,42 @@
+// render package registers additional codecs for content negotiation of
+// phi.Response.Render and phi.Bind: MessagePack, CBOR, YAML and protobuf.
+//
+// Import it for its side effects:
+//
+//	import _ "go.philip.id/phi/render"
+//
+// The codecs are exported as well to register them for other media types:
+//
+//	phi.RegisterCodec("application/vnd.example+yaml", render.YAML)
+//
+// Struct fields are named by their json tags for every codec, so a payload
+// looks the same no matter which representation a client picks.
+package render
+
+import "go.philip.id/phi"
+
+var (
+	// MessagePack encodes values as MessagePack
+	MessagePack phi.Codec = msgpackCodec{}
+
+	// CBOR encodes values as CBOR (RFC 8949)
+	CBOR phi.Codec = cborCodec{}
+
+	// YAML encodes values as YAML 1.2
+	YAML phi.Codec = yamlCodec{}
+
+	// Protobuf encodes values implementing proto.Message as protobuf wire
+	// format, other values are skipped during content negotiation
+	Protobuf phi.Codec = protobufCodec{}
+)
+
+func init() {
+	phi.RegisterCodec("application/msgpack", MessagePack)
+	phi.RegisterCodec("application/x-msgpack", MessagePack)
+	phi.RegisterCodec("application/cbor", CBOR)
+	phi.RegisterCodec("application/yaml", YAML)
+	phi.RegisterCodec("application/x-yaml", YAML)
+	phi.RegisterCodec("text/yaml", YAML)
+	phi.RegisterCodec("application/protobuf", Protobuf)
+	phi.RegisterCodec("application/x-protobuf", Protobuf)
+}
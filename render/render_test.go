@@ -0,0 +1,116 @@
+package render
+
+import (
+	"bytes"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"go.philip.id/phi"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+type user struct {
+	Name string   `json:"name" validate:"required"`
+	Tags []string `json:"tags"`
+}
+
+func TestCodecs(t *testing.T) {
+	r := phi.NewRouter()
+	r.POST("/", func(w *phi.Response, r *phi.Request) *phi.Error {
+		body, err := phi.Bind[user](r)
+		if err != nil {
+			return err
+		}
+
+		return w.Render(body)
+	})
+
+	for _, mediaType := range []string{"application/msgpack", "application/cbor", "application/yaml"} {
+		var in bytes.Buffer
+		if err := mustCodec(t, mediaType).Encode(&in, user{Name: "phi", Tags: []string{"a"}}); err != nil {
+			t.Fatalf("%s: %v", mediaType, err)
+		}
+
+		req := httptest.NewRequest("POST", "/", &in)
+		req.Header.Set("Content-Type", mediaType)
+		req.Header.Set("Accept", mediaType)
+
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+
+		if w.Code != 200 || w.Header().Get("Content-Type") != mediaType {
+			t.Fatalf("%s: unexpected response %d %s %s", mediaType, w.Code, w.Header().Get("Content-Type"), w.Body.String())
+		}
+
+		var out user
+		if err := mustCodec(t, mediaType).Decode(w.Body, &out); err != nil {
+			t.Fatalf("%s: %v", mediaType, err)
+		}
+		if out.Name != "phi" || len(out.Tags) != 1 || out.Tags[0] != "a" {
+			t.Errorf("%s: unexpected body %+v", mediaType, out)
+		}
+	}
+}
+
+func TestYAML(t *testing.T) {
+	var buf bytes.Buffer
+	if err := YAML.Encode(&buf, user{Name: "phi", Tags: []string{"a", "b"}}); err != nil {
+		t.Fatal(err)
+	}
+
+	if expected := "name: phi\ntags:\n  - a\n  - b\n"; buf.String() != expected {
+		t.Errorf("expected %q got %q", expected, buf.String())
+	}
+
+	var out user
+	if err := YAML.Decode(strings.NewReader("name: yaml\n"), &out); err != nil || out.Name != "yaml" {
+		t.Errorf("unexpected decoding %+v %v", out, err)
+	}
+}
+
+func TestProtobuf(t *testing.T) {
+	r := phi.NewRouter()
+	r.GET("/message", func(w *phi.Response, r *phi.Request) *phi.Error {
+		return w.Render(wrapperspb.String("phi"))
+	})
+	r.GET("/struct", func(w *phi.Response, r *phi.Request) *phi.Error {
+		return w.Render(user{Name: "phi"})
+	})
+
+	req := httptest.NewRequest("GET", "/message", nil)
+	req.Header.Set("Accept", "application/protobuf")
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	var out wrapperspb.StringValue
+	if err := Protobuf.Decode(w.Body, &out); err != nil || out.Value != "phi" {
+		t.Errorf("unexpected message %v %v", out.Value, err)
+	}
+
+	// other values can't be encoded as protobuf
+	req = httptest.NewRequest("GET", "/struct", nil)
+	req.Header.Set("Accept", "application/protobuf")
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	if w.Code != 406 {
+		t.Errorf("expected 406 got %d", w.Code)
+	}
+}
+
+func mustCodec(t *testing.T, mediaType string) phi.Codec {
+	switch mediaType {
+	case "application/msgpack":
+		return MessagePack
+	case "application/cbor":
+		return CBOR
+	case "application/yaml":
+		return YAML
+	}
+
+	t.Fatalf("no codec for %s", mediaType)
+	return nil
+}
@@ -63,12 +63,39 @@ func GetContext[T any](r *Request, contextId string) *T {
 //	type Body struct {
 //		Data string `json:"data,required"` 	// required
 //		Dutu string `json:"dutu"`		// optional
+//		Mail string `json:"mail" validate:"required,email"`
+//		Tags []string `json:"tags" validate:"max=10,dive,min=1"`
 //	}
+//
+// Supported rules of the validate tag are required, omitempty, min, max, len,
+// oneof, regexp, email, url, uuid, eqfield and dive, secret hides the rejected
+// value from the error details. See parseRules. Bodies are decoded with the
+// DecodeOptions of the router, see Mux.Decoding.
 func Validate[T any](r *Request) (*T, *Error) {
 	var body T
 
-	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
-		return nil, &decodingError
+	if err := decodeBody(r, jsonCodec{}, &body); err != nil {
+		return nil, err
+	}
+
+	return handleValidate(&body)
+}
+
+// Bind decodes the request body with the codec registered for its
+// Content-Type and validates it like Validate. Bodies without content type
+// are decoded as json, unknown content types result in
+// UnsupportedMediaTypeError.
+//
+//	body, err := phi.Bind[Body](r) // json, xml, ... depending on Content-Type
+func Bind[T any](r *Request) (*T, *Error) {
+	mediaType, codec := lookupCodec(r.Header.Get("Content-Type"))
+	if codec == nil {
+		return nil, UnsupportedMediaTypeError(mediaType)
+	}
+
+	var body T
+	if err := decodeBody(r, codec, &body); err != nil {
+		return nil, err
 	}
 
 	return handleValidate(&body)
@@ -91,11 +118,11 @@ func ValidateString[T any](r string) (*T, *Error) {
 	return handleValidate(&body)
 }
 
-// validates the given datastruct for required fields
+// validates the given datastruct for required fields and `validate:"..."` rules
 func handleValidate[T any](data *T) (*T, *Error) {
-	errS := []string{}
+	errs := []fieldError{}
 
-	if err := handleResolve("", &errS, data); err != nil {
+	if err := handleResolve("", &errs, data); err != nil {
 		return nil, &Error{
 			Error:      "validationFailed",
 			Message:    err.Error(),
@@ -103,9 +130,41 @@ func handleValidate[T any](data *T) (*T, *Error) {
 		}
 	}
 
-	if len(errS) > 0 {
-		return nil, BodyParameterError(fmt.Sprintf("missing '%s'", strings.Join(errS, ", ")))
+	if len(errs) == 0 {
+		return data, nil
+	}
+
+	return nil, validationError(errs, BodyParameterError, InvalidBodyParameterError)
+}
+
+// validationError summarizes the violations as error of newMissing if all
+// of them are missing fields, or of newInvalid otherwise
+func validationError(errs []fieldError, newMissing, newInvalid func(string) *Error) *Error {
+	missing, invalid := []string{}, []string{}
+	details := make([]FieldError, 0, len(errs))
+	for _, e := range errs {
+		if e.rule == "required" {
+			missing = append(missing, e.path)
+		} else {
+			invalid = append(invalid, fmt.Sprintf("'%s' (%s)", e.path, e.message()))
+		}
+
+		details = append(details, FieldError{
+			Path:    e.path,
+			Rule:    e.rule,
+			Message: e.message(),
+			Value:   e.value,
+		})
+	}
+
+	if len(invalid) == 0 {
+		return newMissing(fmt.Sprintf("missing '%s'", strings.Join(missing, ", "))).WithDetails(details...)
+	}
+
+	msg := "invalid " + strings.Join(invalid, ", ")
+	if len(missing) > 0 {
+		msg = fmt.Sprintf("missing '%s'; %s", strings.Join(missing, ", "), msg)
 	}
 
-	return data, nil
+	return newInvalid(msg).WithDetails(details...)
 }
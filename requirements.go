@@ -0,0 +1,90 @@
+package phi
+
+import "net/http"
+
+// Requirements describe what a request has to satisfy to access a route,
+// f.e. the scopes required by middleware.RequireScopes.
+type Requirements struct {
+	// Scopes the token of the request has to carry, all of them
+	Scopes []string `json:"scopes,omitempty"`
+
+	// Roles the token of the request has to carry, one of them
+	Roles []string `json:"roles,omitempty"`
+
+	// Policies name further conditions, f.e. of middleware.Require
+	Policies []string `json:"policies,omitempty"`
+}
+
+// Empty reports whether there aren't any requirements.
+func (rq Requirements) Empty() bool {
+	return len(rq.Scopes) == 0 && len(rq.Roles) == 0 && len(rq.Policies) == 0
+}
+
+// GuardHandler is implemented by handlers of middlewares guarding the routes
+// they wrap, like middleware.RequireScopes. Their requirements are recorded
+// when the route is registered and reported by WalkRequirements and tools
+// like the docgen subpackage.
+type GuardHandler interface {
+	http.Handler
+	Requirements() Requirements
+}
+
+// WalkRequirements walks the routes of r like Walk, passing the requirements
+// of the guarding middlewares of each route, those of Mux.Use of the router
+// and its parents as well as the inline ones of Mux.With:
+//
+//	phi.WalkRequirements(r, func(method, route string, handler http.Handler, rq phi.Requirements) error {
+//		fmt.Println(method, route, rq.Scopes)
+//		return nil
+//	})
+//
+// The requirements are recorded when the middlewares wrap the handlers of
+// the routes, the middlewares aren't called again.
+func WalkRequirements(r Routes, walkFn func(method, route string, handler http.Handler, rq Requirements) error) error {
+	return walk(r, func(method, route string, handler http.Handler, rq Requirements, middlewares ...func(http.Handler) http.Handler) error {
+		return walkFn(method, route, handler, rq)
+	}, "", Requirements{})
+}
+
+// HandlerRequirements returns the requirements of the inline middlewares of
+// a handler of Route.Handlers, see WalkRequirements for those of Mux.Use.
+func HandlerRequirements(h http.Handler) Requirements {
+	if ch, ok := h.(*ChainHandler); ok {
+		return ch.requirements
+	}
+
+	return Requirements{}
+}
+
+// with returns the requirements combined with those of another guard,
+// leaving both unchanged
+func (rq Requirements) with(g Requirements) Requirements {
+	var c Requirements
+	c.merge(rq)
+	c.merge(g)
+	return c
+}
+
+// merge adds the requirements of another guard
+func (rq *Requirements) merge(g Requirements) {
+	rq.Scopes = appendUnique(rq.Scopes, g.Scopes...)
+	rq.Roles = appendUnique(rq.Roles, g.Roles...)
+	rq.Policies = appendUnique(rq.Policies, g.Policies...)
+}
+
+func appendUnique(values []string, add ...string) []string {
+	for _, a := range add {
+		found := false
+		for _, v := range values {
+			if v == a {
+				found = true
+				break
+			}
+		}
+		if !found {
+			values = append(values, a)
+		}
+	}
+
+	return values
+}
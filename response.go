@@ -1,25 +1,29 @@
 package phi
 
 import (
+	"bytes"
 	"encoding/json"
 	"net/http"
 )
 
 type Response struct {
 	http.ResponseWriter
+
+	// request the response belongs to, nil if the Response was created
+	// outside of a Handler
+	request *http.Request
 }
 
 // send response with application/json
 //
-// content will be wrapped in { "data" : <content> } object
+// content will be wrapped by the envelope of the route, by default in a
+// { "data" : <content> } object, see Envelope
 func (w Response) JSON(data interface{}) *Error {
 	if w.Header().Get("Content-Type") == "" {
 		w.Header().Set("Content-Type", "application/json")
 	}
 
-	parsed, err := json.Marshal(Map{
-		"data": data,
-	})
+	parsed, err := json.Marshal(w.envelope(data))
 	if err != nil {
 		return &parseError
 	}
@@ -31,6 +35,36 @@ func (w Response) JSON(data interface{}) *Error {
 	return nil
 }
 
+// send response encoded by the codec matching the Accept header of the
+// request, see RegisterCodec. Requests without Accept header get json.
+//
+// Unlike JSON the content is written as is without { "data": ... } wrapper.
+// If no codec is acceptable NotAcceptableError is returned, f.e.:
+//
+//	func(w *phi.Response, r *phi.Request) *phi.Error {
+//		return w.Render(user) // json, xml, ... depending on Accept
+//	}
+func (w Response) Render(data interface{}) *Error {
+	accept := ""
+	if w.request != nil {
+		accept = w.request.Header.Get("Accept")
+	}
+
+	mediaType, codec := negotiate(accept, data)
+	if codec == nil {
+		return NotAcceptableError(accept)
+	}
+
+	var buf bytes.Buffer
+	if err := codec.Encode(&buf, data); err != nil {
+		return &parseError
+	}
+
+	w.Header().Add("Vary", "Accept")
+
+	return w.Response(buf.Bytes(), mediaType)
+}
+
 // send response with contentType
 func (w Response) Response(data []byte, contentType string) *Error {
 	w.Header().Set("Content-Type", contentType)
@@ -42,7 +76,8 @@ func (w Response) Response(data []byte, contentType string) *Error {
 	return nil
 }
 
-// send error response with status code
+// send error response with status 500 through the error handler of the
+// router, see HandleError
 //
 // default error will look like this:
 //
@@ -50,46 +85,20 @@ func (w Response) Response(data []byte, contentType string) *Error {
 //	  "error": "unknownError",
 //	  "message": err.Error()
 //	}
+//
+// routers with ProblemDetails enabled respond with problem details instead,
+// see ProblemErrorHandler
 func (w Response) Error(err error) *Error {
-	if w.Header().Get("Content-Type") == "" {
-		w.Header().Set("Content-Type", "application/json")
-	}
-
-	parsed, er := json.Marshal(Map{
-		"error":   "unknownError",
-		"message": err.Error(),
-	})
-	if er != nil {
-		return &parseError
-	}
-
-	if _, er = w.Write(parsed); er != nil {
-		return &writingError
-	}
-
+	HandleError(w.ResponseWriter, w.request, UnknownError(err))
 	return nil
 }
 
 // send error response with custom status code
 func (w Response) ErrorCustomStatus(err error, statusCode int) *Error {
-	if w.Header().Get("Content-Type") == "" {
-		w.Header().Set("Content-Type", "application/json")
-	}
-
-	parsed, er := json.Marshal(Map{
-		"error":   "unknownError",
-		"message": err.Error(),
-	})
-	if er != nil {
-		return &parseError
-	}
-
-	w.WriteHeader(statusCode)
-
-	if _, er = w.Write(parsed); er != nil {
-		return &writingError
-	}
+	e := UnknownError(err)
+	e.StatusCode = statusCode
 
+	HandleError(w.ResponseWriter, w.request, e)
 	return nil
 }
 
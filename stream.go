@@ -0,0 +1,344 @@
+package phi
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+var streamingError = Error{
+	Error:      "streamingUnsupported",
+	Message:    "response writer does not support flushing",
+	StatusCode: http.StatusInternalServerError,
+}
+
+// send the values yielded by seq one by one, flushing after every value, so
+// large results don't have to be buffered in memory. The signature matches
+// iter.Seq[any] of newer go versions.
+//
+// Requests accepting application/x-ndjson get newline delimited json, all
+// others a json array. Streaming stops as soon as the request context is
+// cancelled. Unlike JSON the values are not wrapped by the envelope.
+//
+//	return w.Stream(func(yield func(interface{}) bool) {
+//		for rows.Next() {
+//			if !yield(rows.Value()) {
+//				return
+//			}
+//		}
+//	})
+func (w Response) Stream(seq func(yield func(v interface{}) bool)) *Error {
+	ctx := context.Background()
+	ndjson := strings.HasPrefix(w.Header().Get("Content-Type"), "application/x-ndjson")
+	if w.request != nil {
+		ctx = w.request.Context()
+		ndjson = ndjson || acceptsNDJSON(w.request.Header.Get("Accept"))
+	}
+
+	if ndjson {
+		w.Header().Set("Content-Type", "application/x-ndjson")
+	} else if w.Header().Get("Content-Type") == "" {
+		w.Header().Set("Content-Type", "application/json")
+	}
+
+	flusher, _ := w.ResponseWriter.(http.Flusher)
+
+	var (
+		written int
+		err     *Error
+	)
+
+	seq(func(v interface{}) bool {
+		if ctx.Err() != nil {
+			return false
+		}
+
+		parsed, er := json.Marshal(v)
+		if er != nil {
+			err = &parseError
+			return false
+		}
+
+		var buf bytes.Buffer
+		switch {
+		case ndjson:
+			buf.Write(parsed)
+			buf.WriteByte('\n')
+		case written == 0:
+			buf.WriteByte('[')
+			buf.Write(parsed)
+		default:
+			buf.WriteByte(',')
+			buf.Write(parsed)
+		}
+
+		if _, er := w.Write(buf.Bytes()); er != nil {
+			err = &writingError
+			return false
+		}
+		written++
+
+		if flusher != nil {
+			flusher.Flush()
+		}
+
+		return true
+	})
+
+	// the status has been sent already, the error can't be reported anymore
+	if err != nil && written > 0 {
+		log.Printf("#> stream: %s", err.Message)
+		return nil
+	}
+	if err != nil || ctx.Err() != nil || ndjson {
+		return err
+	}
+
+	closing := "]"
+	if written == 0 {
+		closing = "[]"
+	}
+	if _, er := w.Write([]byte(closing)); er != nil {
+		return &writingError
+	}
+
+	return nil
+}
+
+// acceptsNDJSON reports whether the Accept header asks for newline delimited
+// json explicitly, with a higher quality than json
+func acceptsNDJSON(accept string) bool {
+	for _, r := range parseAccept(accept) {
+		if r.q <= 0 {
+			continue
+		}
+
+		switch {
+		case r.mediaType == "application/x-ndjson" || r.mediaType == "application/ndjson":
+			return true
+		case matchMediaRange(r.mediaType, "application/json"):
+			return false
+		}
+	}
+
+	return false
+}
+
+// Event is a single server-sent event, see EventWriter
+type Event struct {
+	// ID sets the last event id of the client, sent as Last-Event-ID when
+	// reconnecting
+	ID string
+
+	// Event is the event type, "message" if empty
+	Event string
+
+	// Retry sets the reconnection time of the client
+	Retry time.Duration
+
+	// Data of the event, strings and byte slices are sent as is, other values
+	// as json
+	Data interface{}
+}
+
+// EventWriter writes server-sent events, it is returned by Response.SSE and
+// safe for concurrent use.
+type EventWriter struct {
+	mu      sync.Mutex
+	w       http.ResponseWriter
+	flusher http.Flusher
+	ctx     context.Context
+	lastID  string
+	closed  bool
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+// ErrEventWriterClosed is returned by EventWriter methods once it is closed
+var ErrEventWriterClosed = errors.New("phi: event writer closed")
+
+// start a text/event-stream response and return the writer for its events.
+// The writer stops writing when the request context is cancelled, Close has
+// to be called before the handler returns.
+//
+//	events, err := w.SSE()
+//	if err != nil {
+//		return err
+//	}
+//	defer events.Close()
+//
+//	events.Heartbeat(15 * time.Second)
+//	for msg := range messages {
+//		if err := events.Send(phi.Event{Event: "message", Data: msg}); err != nil {
+//			return nil
+//		}
+//	}
+func (w Response) SSE() (*EventWriter, *Error) {
+	flusher, ok := w.ResponseWriter.(http.Flusher)
+	if !ok {
+		return nil, &streamingError
+	}
+
+	ctx := context.Background()
+	lastID := ""
+	if w.request != nil {
+		ctx = w.request.Context()
+		lastID = w.request.Header.Get("Last-Event-ID")
+	}
+
+	h := w.Header()
+	h.Set("Content-Type", "text/event-stream")
+	h.Set("Cache-Control", "no-cache")
+	h.Set("Connection", "keep-alive")
+	h.Set("X-Accel-Buffering", "no")
+
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	return &EventWriter{
+		w:       w.ResponseWriter,
+		flusher: flusher,
+		ctx:     ctx,
+		lastID:  lastID,
+	}, nil
+}
+
+// LastEventID returns the Last-Event-ID header sent by reconnecting clients
+func (e *EventWriter) LastEventID() string {
+	return e.lastID
+}
+
+// Done is closed when the request context is cancelled
+func (e *EventWriter) Done() <-chan struct{} {
+	return e.ctx.Done()
+}
+
+// Send writes the event and flushes it to the client
+func (e *EventWriter) Send(ev Event) error {
+	if strings.ContainsAny(ev.ID, "\r\n") || strings.ContainsAny(ev.Event, "\r\n") {
+		return fmt.Errorf("phi: event id and type must not contain newlines")
+	}
+
+	var data string
+	switch v := ev.Data.(type) {
+	case nil:
+	case string:
+		data = v
+	case []byte:
+		data = string(v)
+	default:
+		parsed, err := json.Marshal(v)
+		if err != nil {
+			return err
+		}
+		data = string(parsed)
+	}
+
+	var buf bytes.Buffer
+	if ev.ID != "" {
+		buf.WriteString("id: " + ev.ID + "\n")
+	}
+	if ev.Event != "" {
+		buf.WriteString("event: " + ev.Event + "\n")
+	}
+	if ev.Retry > 0 {
+		buf.WriteString("retry: " + strconv.FormatInt(ev.Retry.Milliseconds(), 10) + "\n")
+	}
+	for _, line := range strings.Split(strings.NewReplacer("\r\n", "\n", "\r", "\n").Replace(data), "\n") {
+		buf.WriteString("data: " + line + "\n")
+	}
+	buf.WriteByte('\n')
+
+	return e.write(buf.Bytes())
+}
+
+// Comment writes a comment line, which is ignored by clients but keeps the
+// connection alive
+func (e *EventWriter) Comment(text string) error {
+	var buf bytes.Buffer
+	for _, line := range strings.Split(text, "\n") {
+		buf.WriteString(": " + line + "\n")
+	}
+	buf.WriteByte('\n')
+
+	return e.write(buf.Bytes())
+}
+
+// Heartbeat sends a comment every interval until the writer is closed or
+// the request context is cancelled, so proxies don't drop idle connections.
+func (e *EventWriter) Heartbeat(interval time.Duration) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if e.closed || e.stop != nil {
+		return
+	}
+
+	e.stop = make(chan struct{})
+	e.done = make(chan struct{})
+
+	go func(stop, done chan struct{}) {
+		defer close(done)
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-stop:
+				return
+			case <-e.ctx.Done():
+				return
+			case <-ticker.C:
+				if e.Comment("heartbeat") != nil {
+					return
+				}
+			}
+		}
+	}(e.stop, e.done)
+}
+
+// Close stops the heartbeat, further events are rejected. It has to be
+// called before the handler returns.
+func (e *EventWriter) Close() error {
+	e.mu.Lock()
+	e.closed = true
+	stop, done := e.stop, e.done
+	e.stop = nil
+	e.mu.Unlock()
+
+	if stop != nil {
+		close(stop)
+		<-done
+	}
+
+	return nil
+}
+
+func (e *EventWriter) write(p []byte) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if e.closed {
+		return ErrEventWriterClosed
+	}
+	if err := e.ctx.Err(); err != nil {
+		return err
+	}
+
+	if _, err := e.w.Write(p); err != nil {
+		return err
+	}
+	e.flusher.Flush()
+
+	return nil
+}
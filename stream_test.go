@@ -0,0 +1,151 @@
+package phi
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestStream(t *testing.T) {
+	r := NewRouter()
+	r.GET("/", func(w *Response, r *Request) *Error {
+		return w.Stream(func(yield func(interface{}) bool) {
+			for i := 1; i <= 3; i++ {
+				if !yield(Map{"n": i}) {
+					return
+				}
+			}
+		})
+	})
+	r.GET("/empty", func(w *Response, r *Request) *Error {
+		return w.Stream(func(yield func(interface{}) bool) {})
+	})
+
+	tests := []struct {
+		path        string
+		accept      string
+		contentType string
+		body        string
+	}{
+		{"/", "", "application/json", `[{"n":1},{"n":2},{"n":3}]`},
+		{"/", "application/x-ndjson", "application/x-ndjson", "{\"n\":1}\n{\"n\":2}\n{\"n\":3}\n"},
+		{"/empty", "", "application/json", `[]`},
+		{"/empty", "application/x-ndjson", "application/x-ndjson", ``},
+		{"/", "application/x-ndjson;q=0.5, application/json", "application/json", `[{"n":1},{"n":2},{"n":3}]`},
+		{"/", "application/json;q=0.8, application/x-ndjson", "application/x-ndjson", "{\"n\":1}\n{\"n\":2}\n{\"n\":3}\n"},
+		{"/", "*/*, application/x-ndjson;q=0.1", "application/json", `[{"n":1},{"n":2},{"n":3}]`},
+	}
+
+	for _, tt := range tests {
+		req := httptest.NewRequest("GET", tt.path, nil)
+		req.Header.Set("Accept", tt.accept)
+
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+
+		if w.Header().Get("Content-Type") != tt.contentType || w.Body.String() != tt.body {
+			t.Errorf("%s %q: unexpected response %s %q", tt.path, tt.accept, w.Header().Get("Content-Type"), w.Body.String())
+		}
+		if tt.path == "/" && !w.Flushed {
+			t.Errorf("%s %q: expected response to be flushed", tt.path, tt.accept)
+		}
+	}
+}
+
+func TestStreamCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	yielded := 0
+	h := Handler(func(w *Response, r *Request) *Error {
+		return w.Stream(func(yield func(interface{}) bool) {
+			for i := 0; i < 10; i++ {
+				if i == 2 {
+					cancel()
+				}
+				if !yield(i) {
+					return
+				}
+				yielded++
+			}
+		})
+	})
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("GET", "/", nil).WithContext(ctx))
+
+	if yielded != 2 || w.Body.String() != "[0,1" {
+		t.Errorf("expected stream to stop after 2 values, got %d %q", yielded, w.Body.String())
+	}
+}
+
+func TestSSE(t *testing.T) {
+	r := NewRouter()
+	r.GET("/", func(w *Response, r *Request) *Error {
+		events, err := w.SSE()
+		if err != nil {
+			return err
+		}
+		defer events.Close()
+
+		events.Send(Event{ID: events.LastEventID() + "1", Event: "greeting", Retry: 3 * time.Second, Data: "hello\nworld"})
+		events.Send(Event{Data: Map{"ok": true}})
+		events.Comment("bye")
+
+		return nil
+	})
+
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set("Last-Event-ID", "4")
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	expected := "id: 41\nevent: greeting\nretry: 3000\ndata: hello\ndata: world\n\n" +
+		"data: {\"ok\":true}\n\n" +
+		": bye\n\n"
+
+	if w.Header().Get("Content-Type") != "text/event-stream" || w.Body.String() != expected {
+		t.Errorf("unexpected response %s %q", w.Header().Get("Content-Type"), w.Body.String())
+	}
+}
+
+func TestSSEHeartbeat(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	w := httptest.NewRecorder()
+	events, err := (Response{ResponseWriter: w, request: httptest.NewRequest("GET", "/", nil).WithContext(ctx)}).SSE()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	events.Heartbeat(5 * time.Millisecond)
+	time.Sleep(30 * time.Millisecond)
+	events.Close()
+
+	if !strings.HasPrefix(w.Body.String(), ": heartbeat\n\n") {
+		t.Errorf("expected heartbeat, got %q", w.Body.String())
+	}
+	if err := events.Send(Event{Data: "late"}); err != ErrEventWriterClosed {
+		t.Errorf("expected closed error, got %v", err)
+	}
+
+	cancel()
+	events, _ = (Response{ResponseWriter: httptest.NewRecorder(), request: httptest.NewRequest("GET", "/", nil).WithContext(ctx)}).SSE()
+	if err := events.Send(Event{Data: "cancelled"}); err != context.Canceled {
+		t.Errorf("expected context error, got %v", err)
+	}
+}
+
+type noFlushWriter struct {
+	http.ResponseWriter
+}
+
+func TestSSEUnsupported(t *testing.T) {
+	if _, err := (Response{ResponseWriter: noFlushWriter{httptest.NewRecorder()}}).SSE(); err == nil || err.Error != "streamingUnsupported" {
+		t.Errorf("expected streaming error, got %v", err)
+	}
+}
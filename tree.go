@@ -111,6 +111,10 @@ type endpoint struct {
 
 	// parameter keys recorded on handler nodes
 	paramKeys []string
+
+	// converters of the typed params along paramKeys, nil if the route
+	// has none
+	converters []*Converter
 }
 
 func (s endpoints) Value(method methodTyp) *endpoint {
@@ -334,26 +338,30 @@ func (n *node) setEndpoint(method methodTyp, handler http.Handler, pattern strin
 	}
 
 	paramKeys := patParamKeys(pattern)
+	convs := patConverters(pattern)
 
 	if method&mSTUB == mSTUB {
 		n.endpoints.Value(mSTUB).handler = handler
 	}
 	if method&mALL == mALL {
 		h := n.endpoints.Value(mALL)
-		h.handler = handler
+		h.handler = mergeMatchHandler(h.handler, handler)
 		h.pattern = pattern
 		h.paramKeys = paramKeys
+		h.converters = convs
 		for _, m := range methodMap {
 			h := n.endpoints.Value(m)
-			h.handler = handler
+			h.handler = mergeMatchHandler(h.handler, handler)
 			h.pattern = pattern
 			h.paramKeys = paramKeys
+			h.converters = convs
 		}
 	} else {
 		h := n.endpoints.Value(method)
-		h.handler = handler
+		h.handler = mergeMatchHandler(h.handler, handler)
 		h.pattern = pattern
 		h.paramKeys = paramKeys
+		h.converters = convs
 	}
 }
 
@@ -372,6 +380,7 @@ func (n *node) FindRoute(rctx *Context, method methodTyp, path string) (*node, e
 	// Record the routing params in the request lifecycle
 	rctx.URLParams.Keys = append(rctx.URLParams.Keys, rctx.routeParams.Keys...)
 	rctx.URLParams.Values = append(rctx.URLParams.Values, rctx.routeParams.Values...)
+	rctx.typedParams = append(rctx.typedParams, rctx.routeTypedParams...)
 
 	// Record the routing pattern in the request lifecycle
 	if rn.endpoints[method].pattern != "" {
@@ -449,14 +458,16 @@ func (n *node) findRoute(rctx *Context, method methodTyp, path string) *node {
 				if len(xsearch) == 0 {
 					if xn.isLeaf() {
 						h := xn.endpoints[method]
-						if h != nil && h.handler != nil {
+						if h != nil && h.handler != nil && h.convert(rctx) {
 							rctx.routeParams.Keys = append(rctx.routeParams.Keys, h.paramKeys...)
 							return xn
 						}
 
 						// flag that the routing context found a route, but not a corresponding
 						// supported method
-						rctx.methodNotAllowed = true
+						if h == nil || h.handler == nil {
+							rctx.methodNotAllowed = true
+						}
 					}
 				}
 
@@ -488,14 +499,16 @@ func (n *node) findRoute(rctx *Context, method methodTyp, path string) *node {
 		if len(xsearch) == 0 {
 			if xn.isLeaf() {
 				h := xn.endpoints[method]
-				if h != nil && h.handler != nil {
+				if h != nil && h.handler != nil && h.convert(rctx) {
 					rctx.routeParams.Keys = append(rctx.routeParams.Keys, h.paramKeys...)
 					return xn
 				}
 
 				// flag that the routing context found a route, but not a corresponding
 				// supported method
-				rctx.methodNotAllowed = true
+				if h == nil || h.handler == nil {
+					rctx.methodNotAllowed = true
+				}
 			}
 		}
 
@@ -545,6 +558,198 @@ func (n *node) findEdge(ntyp nodeTyp, label byte) *node {
 	}
 }
 
+// RemoveRoute removes the endpoint of `method` from the route `pattern`, or
+// all of its endpoints for mALL. Nodes left without endpoints are pruned and
+// static nodes with a single static phild merged again. It reports whether
+// the route had such an endpoint.
+func (n *node) RemoveRoute(method methodTyp, pattern string) bool {
+	path := n.findNodes(pattern)
+	if path == nil || !path[len(path)-1].removeEndpoint(method) {
+		return false
+	}
+
+	pruneNodes(path)
+	return true
+}
+
+// pruneNodes fixes up the edges along the nodes of a route after removing
+// endpoints, from the leaf upwards. The root node is kept as is.
+func pruneNodes(path []*node) {
+	for i := len(path) - 1; i > 0; i-- {
+		parent, nn := path[i-1], path[i]
+		if !nn.isLeaf() && nn.subroutes == nil && nn.numPhildren() == 0 {
+			parent.removeChild(nn)
+			continue
+		}
+		nn.mergeChild()
+	}
+}
+
+// UpdateRoute calls fn with the endpoint of `method` of the route `pattern`,
+// or those of all its methods for mALL, fn reports whether it changed the
+// endpoint. Endpoints fn leaves without handler are removed like with
+// RemoveRoute. It reports whether fn changed any endpoint.
+func (n *node) UpdateRoute(method methodTyp, pattern string, fn func(ep *endpoint) bool) bool {
+	path := n.findNodes(pattern)
+	if path == nil || !path[len(path)-1].isLeaf() {
+		return false
+	}
+	leaf := path[len(path)-1]
+
+	methods := []methodTyp{method}
+	if method == mALL {
+		methods = methods[:0]
+		for mt := range leaf.endpoints {
+			if mt != mSTUB {
+				methods = append(methods, mt)
+			}
+		}
+	}
+
+	changed := false
+	var removed []methodTyp
+	for _, mt := range methods {
+		ep := leaf.endpoints[mt]
+		if ep == nil || ep.handler == nil || !fn(ep) {
+			continue
+		}
+		changed = true
+
+		if ep.handler == nil {
+			removed = append(removed, mt)
+		}
+	}
+
+	if len(removed) > 0 {
+		leaf.deleteEndpoints(removed...)
+		pruneNodes(path)
+	}
+
+	return changed
+}
+
+// findNodes returns the nodes along the route `pattern`, starting with n and
+// ending with the node holding its endpoints, or nil if the route is unknown.
+func (n *node) findNodes(pattern string) []*node {
+	path := []*node{n}
+	search := pattern
+
+	for len(search) > 0 {
+		var label = search[0]
+		var segTail byte
+		var segEndIdx int
+		var segTyp nodeTyp
+		var segRexpat string
+		if label == '{' || label == '*' {
+			segTyp, _, segRexpat, segTail, _, segEndIdx = patNextSegment(search)
+		}
+
+		var prefix string
+		if segTyp == ntRegexp {
+			prefix = segRexpat
+		}
+
+		n = n.getEdge(segTyp, label, segTail, prefix)
+		if n == nil {
+			return nil
+		}
+		path = append(path, n)
+
+		if n.typ > ntStatic {
+			search = search[segEndIdx:]
+			continue
+		}
+
+		if !strings.HasPrefix(search, n.prefix) {
+			return nil
+		}
+		search = search[len(n.prefix):]
+	}
+
+	return path
+}
+
+// removeEndpoint removes the endpoint of the method from the node, as well as
+// its subroutes once no endpoint is left.
+func (n *node) removeEndpoint(method methodTyp) bool {
+	if !n.isLeaf() {
+		return false
+	}
+
+	if method == mALL {
+		n.endpoints = nil
+		n.subroutes = nil
+		return true
+	}
+
+	if h := n.endpoints[method]; h == nil || h.handler == nil {
+		return false
+	}
+
+	n.deleteEndpoints(method)
+	return true
+}
+
+// deleteEndpoints deletes the endpoints of the methods, as well as the
+// subroutes of the node once no endpoint is left.
+func (n *node) deleteEndpoints(methods ...methodTyp) {
+	// the route doesn't match all methods anymore
+	for _, method := range methods {
+		delete(n.endpoints, method)
+	}
+	delete(n.endpoints, mALL)
+
+	for mt, h := range n.endpoints {
+		if mt != mSTUB && h.handler != nil {
+			return
+		}
+	}
+
+	n.endpoints = nil
+	n.subroutes = nil
+}
+
+// removeChild removes the phild node and restores the order of the
+// remaining phildren of its type.
+func (n *node) removeChild(phild *node) {
+	nds := n.phildren[phild.typ]
+	for i := range nds {
+		if nds[i] == phild {
+			nds = append(nds[:i], nds[i+1:]...)
+			break
+		}
+	}
+
+	n.phildren[phild.typ] = nds
+	n.phildren[phild.typ].Sort()
+}
+
+// mergeChild merges a static node without endpoints with its only phild, if
+// that is a static node as well, reverting the split of InsertRoute.
+func (n *node) mergeChild() {
+	if n.typ != ntStatic || n.isLeaf() || n.subroutes != nil || n.numPhildren() != 1 {
+		return
+	}
+
+	phild := n.phildren[ntStatic]
+	if len(phild) != 1 {
+		return
+	}
+
+	n.prefix += phild[0].prefix
+	n.endpoints = phild[0].endpoints
+	n.subroutes = phild[0].subroutes
+	n.phildren = phild[0].phildren
+}
+
+func (n *node) numPhildren() int {
+	num := 0
+	for _, nds := range n.phildren {
+		num += len(nds)
+	}
+	return num
+}
+
 func (n *node) isLeaf() bool {
 	return n.endpoints != nil
 }
@@ -631,7 +836,7 @@ func (n *node) routes() []Route {
 				hs[m] = h.handler
 			}
 
-			rt := Route{subroutes, hs, p}
+			rt := Route{SubRoutes: subroutes, Handlers: hs, Pattern: p}
 			rts = append(rts, rt)
 		}
 
@@ -709,6 +914,11 @@ func patNextSegment(pattern string) (nodeTyp, string, string, byte, int, int) {
 			nt = ntRegexp
 			rexpat = key[idx+1:]
 			key = key[:idx]
+
+			// typed params match the regexp of their converter
+			if c, ok := LookupConverter(rexpat); ok {
+				rexpat = c.Regexp
+			}
 		}
 
 		if len(rexpat) > 0 {
@@ -818,6 +1028,10 @@ type Route struct {
 	SubRoutes Routes
 	Handlers  map[string]http.Handler
 	Pattern   string
+
+	// Host is the host pattern of subrouters registered with Mux.Host,
+	// their Pattern is always "/*"
+	Host string
 }
 
 // WalkFunc is the type of the function called for each method and route visited by Walk.
@@ -825,17 +1039,33 @@ type WalkFunc func(method string, route string, handler http.Handler, middleware
 
 // Walk walks any router tree that implements Routes interface.
 func Walk(r Routes, walkFn WalkFunc) error {
-	return walk(r, walkFn, "")
+	return walk(r, func(method, route string, handler http.Handler, rq Requirements, middlewares ...func(http.Handler) http.Handler) error {
+		return walkFn(method, route, handler, middlewares...)
+	}, "", Requirements{})
 }
 
-func walk(r Routes, walkFn WalkFunc, parentRoute string, parentMw ...func(http.Handler) http.Handler) error {
+// walkRouteFunc is a WalkFunc also passed the requirements of the route
+type walkRouteFunc func(method, route string, handler http.Handler, rq Requirements, middlewares ...func(http.Handler) http.Handler) error
+
+func walk(r Routes, walkFn walkRouteFunc, parentRoute string, parentRq Requirements, parentMw ...func(http.Handler) http.Handler) error {
+	rq := parentRq
+	if mx, ok := r.(*Mux); ok {
+		rq = rq.with(mx.requirements)
+	}
+
 	for _, route := range r.Routes() {
 		mws := make([]func(http.Handler) http.Handler, len(parentMw))
 		copy(mws, parentMw)
 		mws = append(mws, r.Middlewares()...)
 
 		if route.SubRoutes != nil {
-			if err := walk(route.SubRoutes, walkFn, parentRoute+route.Pattern, mws...); err != nil {
+			// routes of hosts are reported as "{tenant}.example.com/users"
+			prefix := parentRoute + route.Pattern
+			if route.Host != "" {
+				prefix = route.Host + strings.TrimSuffix(parentRoute, "/*") + route.Pattern
+			}
+
+			if err := walk(route.SubRoutes, walkFn, prefix, rq, mws...); err != nil {
 				return err
 			}
 			continue
@@ -850,13 +1080,21 @@ func walk(r Routes, walkFn WalkFunc, parentRoute string, parentMw ...func(http.H
 			fullRoute := parentRoute + route.Pattern
 			fullRoute = strings.Replace(fullRoute, "/*/", "/", -1)
 
-			if chain, ok := handler.(*ChainHandler); ok {
-				if err := walkFn(method, fullRoute, chain.Endpoint, append(mws, chain.Middlewares...)...); err != nil {
-					return err
-				}
-			} else {
-				if err := walkFn(method, fullRoute, handler, mws...); err != nil {
-					return err
+			// Visit every endpoint of routes with request predicates
+			handlers := []http.Handler{handler}
+			if mh, ok := handler.(*matchHandler); ok {
+				handlers = mh.handlers()
+			}
+
+			for _, handler := range handlers {
+				if chain, ok := handler.(*ChainHandler); ok {
+					if err := walkFn(method, fullRoute, chain.Endpoint, rq.with(chain.requirements), append(mws, chain.Middlewares...)...); err != nil {
+						return err
+					}
+				} else {
+					if err := walkFn(method, fullRoute, handler, rq, mws...); err != nil {
+						return err
+					}
 				}
 			}
 		}
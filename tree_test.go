@@ -444,6 +444,92 @@ func TestTreeFindPattern(t *testing.T) {
 	}
 }
 
+func TestTreeRemoveRoute(t *testing.T) {
+	hUsers := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+	hUploads := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+	hUser := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+	hUserNum := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+	hUserFiles := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+	hAll := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+
+	tr := &node{}
+	tr.InsertRoute(mGET, "/users", hUsers)
+	tr.InsertRoute(mGET, "/uploads", hUploads)
+	tr.InsertRoute(mGET, "/users/{id}", hUser)
+	tr.InsertRoute(mGET, "/users/{id:[0-9]+}", hUserNum)
+	tr.InsertRoute(mGET, "/users/{id}/files/*", hUserFiles)
+	tr.InsertRoute(mALL, "/all", hAll)
+
+	if tr.RemoveRoute(mGET, "/upload") || tr.RemoveRoute(mPOST, "/uploads") || tr.RemoveRoute(mGET, "/users/{id}/files") {
+		t.Fatal("expected unknown routes not to be removed")
+	}
+
+	if !tr.RemoveRoute(mGET, "/uploads") {
+		t.Fatal("expected /uploads to be removed")
+	}
+	// "/u" is merged with its only phild "sers" again
+	if nds := tr.phildren[ntStatic]; len(nds) != 1 || nds[0].prefix != "/" || nds[0].phildren[ntStatic][1].prefix != "users" {
+		t.Fatalf("expected split nodes to be merged")
+	}
+
+	if !tr.RemoveRoute(mGET, "/users/{uid:[0-9]+}") {
+		t.Fatal("expected /users/{id:[0-9]+} to be removed")
+	}
+	if !tr.RemoveRoute(mPOST, "/all") {
+		t.Fatal("expected POST /all to be removed")
+	}
+
+	tests := []struct {
+		m methodTyp
+		r string
+		h http.Handler
+		k []string
+		v []string
+	}{
+		{m: mGET, r: "/users", h: hUsers, k: []string{}, v: []string{}},
+		{m: mGET, r: "/uploads", h: nil, k: []string{}, v: []string{}},
+		{m: mGET, r: "/users/1", h: hUser, k: []string{"id"}, v: []string{"1"}},
+		{m: mGET, r: "/users/1/files/a/b", h: hUserFiles, k: []string{"id", "*"}, v: []string{"1", "a/b"}},
+		{m: mGET, r: "/all", h: hAll, k: []string{}, v: []string{}},
+		{m: mPOST, r: "/all", h: nil, k: []string{}, v: []string{}},
+	}
+
+	check := func() {
+		for i, tt := range tests {
+			rctx := NewRouteContext()
+
+			_, _, handler := tr.FindRoute(rctx, tt.m, tt.r)
+
+			if fmt.Sprintf("%v", tt.h) != fmt.Sprintf("%v", handler) {
+				t.Errorf("input [%d]: find '%s' expecting handler:%v , got:%v", i, tt.r, tt.h, handler)
+			}
+			if tt.h != nil && !stringSliceEqual(tt.k, rctx.routeParams.Keys) {
+				t.Errorf("input [%d]: find '%s' expecting paramKeys:%v , got:%v", i, tt.r, tt.k, rctx.routeParams.Keys)
+			}
+			if tt.h != nil && !stringSliceEqual(tt.v, rctx.routeParams.Values) {
+				t.Errorf("input [%d]: find '%s' expecting paramValues:%v , got:%v", i, tt.r, tt.v, rctx.routeParams.Values)
+			}
+		}
+	}
+	check()
+
+	// removing every route prunes the tree down to the root
+	for _, pattern := range []string{"/users/{id}/files/*", "/users/{id}", "/users"} {
+		if !tr.RemoveRoute(mGET, pattern) {
+			t.Fatalf("expected %s to be removed", pattern)
+		}
+	}
+	if !tr.RemoveRoute(mALL, "/all") {
+		t.Fatal("expected /all to be removed")
+	}
+	if tr.numPhildren() != 0 {
+		t.Errorf("expected empty tree, got %d phildren", tr.numPhildren())
+	}
+	if len(tr.routes()) != 0 {
+		t.Errorf("expected no routes, got %v", tr.routes())
+	}
+}
+
 func debugPrintTree(parent int, i int, n *node, label byte) bool {
 	numEdges := 0
 	for _, nds := range n.phildren {
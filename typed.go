@@ -0,0 +1,100 @@
+package phi
+
+import (
+	"context"
+	"net/http"
+	"reflect"
+)
+
+// Get registers a GET route on r which responds with the *Out returned by fn,
+// written via Response.JSON.
+//
+// URL parameters are available via URLParamFromCtx.
+//
+// Example:
+//
+//	phi.Get(r, "/users/{id}", func(ctx context.Context) (*User, *phi.Error) {
+//		return users.Find(ctx, phi.URLParamFromCtx(ctx, "id"))
+//	})
+func Get[Out any](r Router, pattern string, fn func(ctx context.Context) (*Out, *Error)) *RouteRef {
+	return r.Method(http.MethodGet, pattern, typedNoBody(fn))
+}
+
+// Delete registers a DELETE route on r which responds with the *Out returned
+// by fn, written via Response.JSON.
+func Delete[Out any](r Router, pattern string, fn func(ctx context.Context) (*Out, *Error)) *RouteRef {
+	return r.Method(http.MethodDelete, pattern, typedNoBody(fn))
+}
+
+// Post registers a POST route on r. The request body is decoded and validated
+// into *In like Validate does before fn is called, the returned *Out is written
+// via Response.JSON.
+//
+// Example:
+//
+//	phi.Post(r, "/users", func(ctx context.Context, in *CreateUser) (*User, *phi.Error) {
+//		return users.Create(ctx, in)
+//	})
+func Post[In, Out any](r Router, pattern string, fn func(ctx context.Context, in *In) (*Out, *Error)) *RouteRef {
+	return r.Method(http.MethodPost, pattern, typedBody(fn))
+}
+
+// Put registers a PUT route on r, see Post.
+func Put[In, Out any](r Router, pattern string, fn func(ctx context.Context, in *In) (*Out, *Error)) *RouteRef {
+	return r.Method(http.MethodPut, pattern, typedBody(fn))
+}
+
+// Patch registers a PATCH route on r, see Post.
+func Patch[In, Out any](r Router, pattern string, fn func(ctx context.Context, in *In) (*Out, *Error)) *RouteRef {
+	return r.Method(http.MethodPatch, pattern, typedBody(fn))
+}
+
+// HandlerTypes returns the request and response types recorded on h by one
+// of the typed helpers, unwrapping inline middleware chains. Unknown types
+// are returned as nil.
+func HandlerTypes(h http.Handler) (in, out reflect.Type) {
+	if ch, ok := h.(*ChainHandler); ok {
+		h = ch.Endpoint
+	}
+
+	th, ok := h.(TypedHandler)
+	if !ok {
+		return nil, nil
+	}
+
+	return th.RequestType(), th.ResponseType()
+}
+
+func typedBody[In, Out any](fn func(ctx context.Context, in *In) (*Out, *Error)) *typedHandler {
+	return &typedHandler{
+		handler: Handler(func(w *Response, r *Request) *Error {
+			in, err := Validate[In](r)
+			if err != nil {
+				return err
+			}
+
+			out, err := fn(r.Context(), in)
+			if err != nil {
+				return err
+			}
+
+			return w.JSON(out)
+		}),
+		in:  typeOf[In](),
+		out: typeOf[Out](),
+	}
+}
+
+func typedNoBody[Out any](fn func(ctx context.Context) (*Out, *Error)) *typedHandler {
+	return &typedHandler{
+		handler: Handler(func(w *Response, r *Request) *Error {
+			out, err := fn(r.Context())
+			if err != nil {
+				return err
+			}
+
+			return w.JSON(out)
+		}),
+		out: typeOf[Out](),
+	}
+}
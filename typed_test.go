@@ -0,0 +1,86 @@
+package phi
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+type typedIn struct {
+	Name string `json:"name,required"`
+}
+
+type typedOut struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+func TestTypedHandlers(t *testing.T) {
+	r := NewRouter()
+
+	Post(r, "/users", func(ctx context.Context, in *typedIn) (*typedOut, *Error) {
+		return &typedOut{ID: "1", Name: in.Name}, nil
+	})
+
+	r.Route("/users/{id}", func(r Router) {
+		Get(r, "/", func(ctx context.Context) (*typedOut, *Error) {
+			return &typedOut{ID: URLParamFromCtx(ctx, "id")}, nil
+		})
+
+		Delete(r, "/", func(ctx context.Context) (*typedOut, *Error) {
+			return nil, URLParameterError("id")
+		})
+	})
+
+	ts := httptest.NewServer(r)
+	defer ts.Close()
+
+	if _, body := testRequest(t, ts, "POST", "/users", bytes.NewBufferString(`{"name":"phi"}`)); body != `{"data":{"id":"1","name":"phi"}}` {
+		t.Errorf("unexpected body %s", body)
+	}
+
+	if resp, body := testRequest(t, ts, "POST", "/users", bytes.NewBufferString(`{}`)); resp.StatusCode != 400 ||
+		body != `{"error":"missingBodyParameters","errors":[{"path":"name","rule":"required","message":"is required"}],"message":"missing 'name'"}` {
+		t.Errorf("unexpected response %d %s", resp.StatusCode, body)
+	}
+
+	if _, body := testRequest(t, ts, "GET", "/users/42", nil); body != `{"data":{"id":"42","name":""}}` {
+		t.Errorf("unexpected body %s", body)
+	}
+
+	if _, body := testRequest(t, ts, "DELETE", "/users/42", nil); body != `{"error":"missingURLParameters","message":"id"}` {
+		t.Errorf("unexpected body %s", body)
+	}
+}
+
+func TestHandlerTypes(t *testing.T) {
+	r := NewRouter()
+
+	r.With(func(next http.Handler) http.Handler { return next }).Group(func(r Router) {
+		Put(r, "/users", func(ctx context.Context, in *typedIn) (*typedOut, *Error) {
+			return nil, nil
+		})
+	})
+	Get(r, "/users", func(ctx context.Context) (*[]typedOut, *Error) {
+		return nil, nil
+	})
+
+	types := map[string][2]reflect.Type{}
+	for _, route := range r.Routes() {
+		for method, h := range route.Handlers {
+			in, out := HandlerTypes(h)
+			types[method] = [2]reflect.Type{in, out}
+		}
+	}
+
+	if tt := types["PUT"]; tt[0] != reflect.TypeOf(typedIn{}) || tt[1] != reflect.TypeOf(typedOut{}) {
+		t.Errorf("unexpected PUT types %v", tt)
+	}
+
+	if tt := types["GET"]; tt[0] != nil || tt[1] != reflect.TypeOf([]typedOut{}) {
+		t.Errorf("unexpected GET types %v", tt)
+	}
+}
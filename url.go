@@ -0,0 +1,158 @@
+package phi
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// RouteRef refers to a route registered on a Mux, it is returned by the
+// route registrars to name the route:
+//
+//	r.Get("/users/{id}/orders", listOrders).Name("user.orders")
+type RouteRef struct {
+	mux     *Mux
+	pattern string
+}
+
+// Pattern returns the routing pattern of the route, relative to the Mux it
+// was registered on.
+func (rr *RouteRef) Pattern() string {
+	return rr.pattern
+}
+
+// Name names the route to build URLs to it with Mux.URL. Names must be
+// unique per router.
+func (rr *RouteRef) Name(name string) *RouteRef {
+	mx := rr.mux
+	if mx.names == nil {
+		mx.names = map[string]string{}
+	}
+
+	if pattern, ok := mx.names[name]; ok && pattern != rr.pattern {
+		panic(fmt.Sprintf("phi: route name '%s' is already used by '%s'", name, pattern))
+	}
+	mx.names[name] = rr.pattern
+
+	return rr
+}
+
+// route returns the reference to the route `pattern`, names of inline
+// muxes are kept by the router they belong to
+func (mx *Mux) route(pattern string) *RouteRef {
+	m := mx
+	for m.inline && m.parent != nil {
+		m = m.parent
+	}
+
+	return &RouteRef{mux: m, pattern: pattern}
+}
+
+// URL builds the path of the route named `name`, replacing its URL
+// parameters by the given key value pairs. Routes of subrouters are found
+// including the patterns they are mounted on, values have to match the
+// regexp of their parameter:
+//
+//	r.Route("/users/{id:\\d+}", func(r phi.Router) {
+//		r.Get("/orders", listOrders).Name("user.orders")
+//	})
+//
+//	r.URL("user.orders", "id", "42") // "/users/42/orders"
+//
+// The wildcard of catch-all routes is set with the key "*".
+func (mx *Mux) URL(name string, params ...string) (string, error) {
+	if len(params)%2 != 0 {
+		return "", fmt.Errorf("phi: URL parameters of route '%s' must be key value pairs", name)
+	}
+
+	pattern, ok := mx.findName(name)
+	if !ok {
+		return "", fmt.Errorf("phi: unknown route name '%s'", name)
+	}
+
+	values := make(map[string]string, len(params)/2)
+	for i := 0; i < len(params); i += 2 {
+		values[params[i]] = params[i+1]
+	}
+
+	return buildURL(pattern, values)
+}
+
+// findName returns the full pattern of the named route, searching mounted
+// subrouters as well
+func (mx *Mux) findName(name string) (string, bool) {
+	if pattern, ok := mx.names[name]; ok {
+		return pattern, true
+	}
+
+	for _, route := range mx.Routes() {
+		sub, ok := route.SubRoutes.(interface {
+			findName(name string) (string, bool)
+		})
+		if !ok {
+			continue
+		}
+
+		if pattern, ok := sub.findName(name); ok {
+			return strings.TrimSuffix(route.Pattern, "/*") + pattern, true
+		}
+	}
+
+	return "", false
+}
+
+// buildURL replaces the parameters of the routing pattern by their values
+func buildURL(pattern string, values map[string]string) (string, error) {
+	var b strings.Builder
+
+	for pattern != "" {
+		typ, key, rexpat, _, ps, pe := patNextSegment(pattern)
+		if typ == ntStatic {
+			b.WriteString(pattern)
+			break
+		}
+		b.WriteString(pattern[:ps])
+		pattern = pattern[pe:]
+
+		value, ok := values[key]
+		if !ok && typ != ntCatchAll {
+			return "", fmt.Errorf("phi: missing URL parameter '%s'", key)
+		}
+
+		if typ == ntRegexp {
+			rex, err := compileRegexp(rexpat)
+			if err != nil {
+				return "", err
+			}
+			if !rex.MatchString(value) {
+				return "", fmt.Errorf("phi: URL parameter '%s' does not match '%s'", key, rexpat)
+			}
+		}
+
+		if typ != ntCatchAll {
+			b.WriteString(url.PathEscape(value))
+			continue
+		}
+
+		// the wildcard may span several path segments
+		segments := strings.Split(value, "/")
+		for i, s := range segments {
+			segments[i] = url.PathEscape(s)
+		}
+		b.WriteString(strings.Join(segments, "/"))
+	}
+
+	return b.String(), nil
+}
+
+// URL builds the path of a named route of the router handling the request,
+// see Mux.URL.
+func (x *Context) URL(name string, params ...string) (string, error) {
+	if u, ok := x.Routes.(interface {
+		URL(name string, params ...string) (string, error)
+	}); ok {
+		return u.URL(name, params...)
+	}
+
+	return "", fmt.Errorf("phi: unknown route name '%s'", name)
+}
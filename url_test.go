@@ -0,0 +1,70 @@
+package phi
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestURL(t *testing.T) {
+	r := NewRouter()
+	r.Get("/", func(w http.ResponseWriter, r *http.Request) {}).Name("home")
+	r.With(func(next http.Handler) http.Handler { return next }).Get("/about", func(w http.ResponseWriter, r *http.Request) {}).Name("about")
+
+	r.Route("/users/{id:\\d+}", func(r Router) {
+		r.Get("/orders/{orderID}", func(w http.ResponseWriter, r *http.Request) {}).Name("user.order")
+		r.Group(func(r Router) {
+			r.GET("/files/*", func(w *Response, r *Request) *Error { return nil }).Name("user.files")
+		})
+	})
+
+	admin := NewRouter()
+	admin.GET("/", func(w *Response, r *Request) *Error {
+		u, err := RouteContext(r.Context()).URL("user.order", "id", "1", "orderID", "2")
+		if err != nil {
+			return UnknownError(err)
+		}
+		return w.Response([]byte(u), "text/plain")
+	}).Name("admin")
+	r.Mount("/admin", admin)
+
+	tests := []struct {
+		name   string
+		params []string
+		url    string
+		err    bool
+	}{
+		{"home", nil, "/", false},
+		{"about", nil, "/about", false},
+		{"user.order", []string{"id", "42", "orderID", "a b"}, "/users/42/orders/a%20b", false},
+		{"user.order", []string{"id", "x", "orderID", "1"}, "", true},
+		{"user.order", []string{"id", "42"}, "", true},
+		{"user.order", []string{"id"}, "", true},
+		{"user.files", []string{"id", "1", "*", "docs/a b.pdf"}, "/users/1/files/docs/a%20b.pdf", false},
+		{"user.files", []string{"id", "1"}, "/users/1/files/", false},
+		{"admin", nil, "/admin/", false},
+		{"unknown", nil, "", true},
+	}
+
+	for _, tt := range tests {
+		u, err := r.URL(tt.name, tt.params...)
+		if (err != nil) != tt.err || u != tt.url {
+			t.Errorf("%s %v: expected %q (error %v), got %q %v", tt.name, tt.params, tt.url, tt.err, u, err)
+		}
+	}
+
+	if _, body := testHandler(t, r, "GET", "/admin/", nil); body != "/users/1/orders/2" {
+		t.Errorf("unexpected url from route context %q", body)
+	}
+}
+
+func TestURLDuplicateName(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("expected panic for duplicate route name")
+		}
+	}()
+
+	r := NewRouter()
+	r.Get("/a", func(w http.ResponseWriter, r *http.Request) {}).Name("a")
+	r.Get("/b", func(w http.ResponseWriter, r *http.Request) {}).Name("a")
+}
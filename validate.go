@@ -2,12 +2,45 @@ package phi
 
 import (
 	"fmt"
+	"net/mail"
+	"net/url"
 	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
 	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+// fieldError describes a single rule violation of a field, identified by
+// its JSON path like items[2].name
+type fieldError struct {
+	path  string
+	rule  string
+	param string
+	value interface{}
+}
+
+// rule is a single entry of a `validate:"..."` struct tag
+type rule struct {
+	name  string
+	param string
+}
+
+var (
+	uuidRegexp = regexp.MustCompile("^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$")
+
+	// compiled regexp rules, keyed by their pattern
+	regexpCache sync.Map
 )
 
 // validates the data structure
-func handleResolve(prefix string, errs *[]string, data interface{}) error {
+func handleResolve(prefix string, errs *[]fieldError, data interface{}) error {
+	if data == nil {
+		return nil
+	}
+
 	switch reflect.TypeOf(data).Kind() {
 	case reflect.Struct:
 		if err := handleStruct(prefix, errs, data); err != nil {
@@ -34,32 +67,126 @@ func handleResolve(prefix string, errs *[]string, data interface{}) error {
 }
 
 // handle struct type validation
-func handleStruct(prefix string, errs *[]string, data interface{}) error {
+func handleStruct(prefix string, errs *[]fieldError, data interface{}) error {
 	fields := reflect.ValueOf(data)
 	for i := 0; i < fields.NumField(); i++ {
-		jsonTags := fields.Type().Field(i).Tag.Get("json")
+		field := fields.Type().Field(i)
+		if !field.IsExported() {
+			continue
+		}
+
+		jsonTags := strings.Split(field.Tag.Get("json"), ",")
+		if jsonTags[0] == "-" {
+			continue
+		}
 
-		tag := strings.Split(jsonTags, ",")[0]
+		tag := jsonTags[0]
+		if tag == "" {
+			tag = field.Name
+		}
 		if prefix != "" {
 			tag = prefix + "." + tag
 		}
 
-		// check if tags contain required and the value is zero
-		if strings.Contains(jsonTags, "required") && fields.Field(i).IsZero() {
-			*errs = append(*errs, tag)
-		} else {
-			// no error, validate underlying type(s)
-			if err := handleResolve(tag, errs, fields.Field(i).Interface()); err != nil {
-				return err
+		rules, err := parseRules(field.Tag.Get("validate"))
+		if err != nil {
+			return fmt.Errorf("field %s: %w", tag, err)
+		}
+
+		// json:",required" is kept as shorthand of validate:"required"
+		for _, opt := range jsonTags[1:] {
+			if opt == "required" {
+				rules = append([]rule{{name: "required"}}, rules...)
+				break
 			}
 		}
+
+		if err := handleField(tag, errs, fields.Field(i), rules, fields); err != nil {
+			return err
+		}
 	}
 
 	return nil
 }
 
+// handle validation of a single value against the rules, then validate
+// the underlying type(s). Rules after `dive` are applied to the elements
+// of slices, arrays and maps.
+func handleField(path string, errs *[]fieldError, v reflect.Value, rules []rule, parent reflect.Value) error {
+	secret := hasRule(rules, "secret")
+	for i, r := range rules {
+		switch r.name {
+		case "secret":
+			continue
+
+		case "required":
+			if v.IsZero() {
+				*errs = append(*errs, fieldError{path: path, rule: r.name})
+				return nil
+			}
+			continue
+
+		case "omitempty":
+			if v.IsZero() {
+				return nil
+			}
+			continue
+
+		case "dive":
+			elem := indirectValue(v)
+			if !elem.IsValid() {
+				return nil
+			}
+
+			elemRules := rules[i+1:]
+			if secret {
+				elemRules = append([]rule{{name: "secret"}}, elemRules...)
+			}
+
+			switch elem.Kind() {
+			case reflect.Slice, reflect.Array:
+				for j := 0; j < elem.Len(); j++ {
+					if err := handleField(path+fmt.Sprintf("[%d]", j), errs, elem.Index(j), elemRules, parent); err != nil {
+						return err
+					}
+				}
+			case reflect.Map:
+				for _, key := range sortedMapKeys(elem) {
+					if err := handleField(path+fmt.Sprintf("[%v]", key.Interface()), errs, elem.MapIndex(key), elemRules, parent); err != nil {
+						return err
+					}
+				}
+			default:
+				return fmt.Errorf("field %s: dive on %s", path, elem.Kind())
+			}
+			return nil
+		}
+
+		ok, err := checkRule(r, v, parent)
+		if err != nil {
+			return fmt.Errorf("field %s: %w", path, err)
+		}
+
+		if !ok {
+			var value interface{}
+			if elem := indirectValue(v); !secret && elem.IsValid() && elem.CanInterface() {
+				value = elem.Interface()
+			}
+
+			*errs = append(*errs, fieldError{path: path, rule: r.name, param: r.param, value: value})
+		}
+	}
+
+	// no error, validate underlying type(s)
+	if !v.CanInterface() {
+		return nil
+	}
+
+	return handleResolve(path, errs, v.Interface())
+}
+
 // handle slice and array type validation
-func handleSliceArray(prefix string, errs *[]string, data interface{}) error {
+func handleSliceArray(prefix string, errs *[]fieldError, data interface{}) error {
 	// get the type of the slice/array and resolve pointer
 	v := reflect.ValueOf(data)
 
@@ -80,10 +207,10 @@ func handleSliceArray(prefix string, errs *[]string, data interface{}) error {
 }
 
 // handle map type validation
-func handleMap(prefix string, errs *[]string, data interface{}) error {
+func handleMap(prefix string, errs *[]fieldError, data interface{}) error {
 	v := reflect.ValueOf(data)
 
-	for _, key := range v.MapKeys() {
+	for _, key := range sortedMapKeys(v) {
 		// get the kind of the key and resolve pointer values
 		val := v.MapIndex(key)
 		if err := handleResolve(prefix+fmt.Sprintf("[%v]", key.Interface()), errs, val.Interface()); err != nil {
@@ -93,3 +220,250 @@ func handleMap(prefix string, errs *[]string, data interface{}) error {
 
 	return nil
 }
+
+// sortedMapKeys returns the keys of the map value ordered by their string
+// representation, so violations are reported in a stable order
+func sortedMapKeys(v reflect.Value) []reflect.Value {
+	keys := v.MapKeys()
+	sort.Slice(keys, func(i, j int) bool {
+		return fmt.Sprint(keys[i].Interface()) < fmt.Sprint(keys[j].Interface())
+	})
+
+	return keys
+}
+
+// hasRule reports whether the rules contain the rule `name`
+func hasRule(rules []rule, name string) bool {
+	for _, r := range rules {
+		if r.name == name {
+			return true
+		}
+	}
+
+	return false
+}
+
+// parseRules parses a `validate:"..."` tag. Rules are separated by commas,
+// `regexp=` consumes the rest of the tag so it has to be the last rule.
+// `secret` keeps the rejected value of the field out of the error details,
+// f.e. of passwords or tokens.
+//
+//	validate:"required,min=3,max=32,oneof=red green blue"
+//	validate:"omitempty,regexp=^[a-z]{2,4}$"
+//	validate:"required,secret,min=12"
+func parseRules(tag string) ([]rule, error) {
+	rules := []rule{}
+
+	for tag != "" {
+		var r string
+		if strings.HasPrefix(tag, "regexp=") {
+			r, tag = tag, ""
+		} else if idx := strings.IndexByte(tag, ','); idx >= 0 {
+			r, tag = tag[:idx], tag[idx+1:]
+		} else {
+			r, tag = tag, ""
+		}
+
+		name, param, _ := strings.Cut(strings.TrimSpace(r), "=")
+		switch name {
+		case "":
+			continue
+		case "required", "omitempty", "dive", "email", "url", "uuid", "secret":
+			if param != "" {
+				return nil, fmt.Errorf("rule '%s' takes no parameter", name)
+			}
+		case "min", "max", "len":
+			if _, err := strconv.ParseFloat(param, 64); err != nil {
+				return nil, fmt.Errorf("rule '%s' needs a numeric parameter", name)
+			}
+		case "oneof", "eqfield":
+			if param == "" {
+				return nil, fmt.Errorf("rule '%s' needs a parameter", name)
+			}
+		case "regexp":
+			if _, err := compileRegexp(param); err != nil {
+				return nil, err
+			}
+		default:
+			return nil, fmt.Errorf("unknown validation rule '%s'", name)
+		}
+
+		rules = append(rules, rule{name: name, param: param})
+	}
+
+	return rules, nil
+}
+
+// checkRule reports whether v satisfies the rule. Nil pointers satisfy every
+// rule, use `required` to enforce a value.
+func checkRule(r rule, v reflect.Value, parent reflect.Value) (bool, error) {
+	v = indirectValue(v)
+	if !v.IsValid() {
+		return true, nil
+	}
+
+	switch r.name {
+	case "min", "max", "len":
+		limit, _ := strconv.ParseFloat(r.param, 64)
+
+		n, ok := measure(v)
+		if !ok {
+			return false, fmt.Errorf("rule '%s' not applicable to %s", r.name, v.Kind())
+		}
+
+		switch r.name {
+		case "min":
+			return n >= limit, nil
+		case "max":
+			return n <= limit, nil
+		}
+		return n == limit, nil
+
+	case "oneof":
+		s := fmt.Sprint(v.Interface())
+		for _, option := range strings.Fields(r.param) {
+			if s == option {
+				return true, nil
+			}
+		}
+		return false, nil
+
+	case "eqfield":
+		other := parent.FieldByName(r.param)
+		if !other.IsValid() {
+			other = fieldByJSONName(parent, r.param)
+		}
+		if !other.IsValid() {
+			return false, fmt.Errorf("eqfield: unknown field '%s'", r.param)
+		}
+
+		other = indirectValue(other)
+		return other.IsValid() && reflect.DeepEqual(v.Interface(), other.Interface()), nil
+	}
+
+	if v.Kind() != reflect.String {
+		return false, fmt.Errorf("rule '%s' not applicable to %s", r.name, v.Kind())
+	}
+	s := v.String()
+
+	switch r.name {
+	case "regexp":
+		rex, err := compileRegexp(r.param)
+		if err != nil {
+			return false, err
+		}
+		return rex.MatchString(s), nil
+
+	case "email":
+		addr, err := mail.ParseAddress(s)
+		return err == nil && addr.Address == s, nil
+
+	case "url":
+		u, err := url.Parse(s)
+		return err == nil && u.Scheme != "" && u.Host != "", nil
+
+	case "uuid":
+		return uuidRegexp.MatchString(s), nil
+	}
+
+	return false, fmt.Errorf("unknown validation rule '%s'", r.name)
+}
+
+// measure returns the value of numbers and the length of strings, slices,
+// arrays and maps
+func measure(v reflect.Value) (float64, bool) {
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(v.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return float64(v.Uint()), true
+	case reflect.Float32, reflect.Float64:
+		return v.Float(), true
+	case reflect.String:
+		return float64(utf8.RuneCountInString(v.String())), true
+	case reflect.Slice, reflect.Array, reflect.Map:
+		return float64(v.Len()), true
+	}
+
+	return 0, false
+}
+
+// isNumber reports whether rules like min and max compare the value rather
+// than the length of v
+func isNumber(v interface{}) bool {
+	switch reflect.ValueOf(v).Kind() {
+	case reflect.String, reflect.Slice, reflect.Array, reflect.Map, reflect.Invalid:
+		return false
+	}
+
+	return true
+}
+
+// fieldByJSONName finds the field of the struct value tagged with name
+func fieldByJSONName(v reflect.Value, name string) reflect.Value {
+	for i := 0; i < v.NumField(); i++ {
+		if tag, _, _ := strings.Cut(v.Type().Field(i).Tag.Get("json"), ","); tag == name {
+			return v.Field(i)
+		}
+	}
+
+	return reflect.Value{}
+}
+
+// indirectValue dereferences pointers and interfaces, returns the zero
+// Value for nil
+func indirectValue(v reflect.Value) reflect.Value {
+	for v.IsValid() && (v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface) {
+		if v.IsNil() {
+			return reflect.Value{}
+		}
+		v = v.Elem()
+	}
+
+	return v
+}
+
+func compileRegexp(pattern string) (*regexp.Regexp, error) {
+	if rex, ok := regexpCache.Load(pattern); ok {
+		return rex.(*regexp.Regexp), nil
+	}
+
+	rex, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("invalid regexp '%s': %w", pattern, err)
+	}
+	regexpCache.Store(pattern, rex)
+
+	return rex, nil
+}
+
+// message returns a human readable description of the violation
+func (e fieldError) message() string {
+	switch e.rule {
+	case "required":
+		return "is required"
+	case "min":
+		if isNumber(e.value) {
+			return "must be at least " + e.param
+		}
+		return "length must be at least " + e.param
+	case "max":
+		if isNumber(e.value) {
+			return "must be at most " + e.param
+		}
+		return "length must be at most " + e.param
+	case "len":
+		if isNumber(e.value) {
+			return "must be " + e.param
+		}
+		return "length must be " + e.param
+	case "oneof":
+		return "must be one of [" + e.param + "]"
+	case "regexp":
+		return "must match " + e.param
+	case "eqfield":
+		return "must be equal to " + e.param
+	}
+
+	return "must be a valid " + e.rule
+}
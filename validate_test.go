@@ -220,3 +220,115 @@ func TestPlayground(t *testing.T) {
 	})
 	spew.Dump(err)
 }
+
+type TestRulesItem struct {
+	Name string `json:"name" validate:"required,min=3"`
+}
+
+type TestRulesStruct struct {
+	NotRequired string            `json:"notrequired"`
+	Age         int               `json:"age" validate:"min=18,max=130"`
+	Code        string            `json:"code" validate:"len=4"`
+	Color       string            `json:"color" validate:"omitempty,oneof=red green blue"`
+	Slug        string            `json:"slug" validate:"regexp=^[a-z]{2,4}$"`
+	Email       string            `json:"email" validate:"email"`
+	Website     *string           `json:"website" validate:"url"`
+	ID          string            `json:"id" validate:"uuid"`
+	Password    string            `json:"password"`
+	Confirm     string            `json:"confirm" validate:"eqfield=Password"`
+	Items       []TestRulesItem   `json:"items" validate:"max=3"`
+	Tags        []string          `json:"tags" validate:"dive,min=2"`
+	Labels      map[string]string `json:"labels" validate:"dive,oneof=a b"`
+}
+
+func validRulesStruct() TestRulesStruct {
+	website := "https://philip.id"
+
+	return TestRulesStruct{
+		Age:      20,
+		Code:     "abcd",
+		Slug:     "phi",
+		Email:    "phi@philip.id",
+		Website:  &website,
+		ID:       "0b9d6f0e-5d6f-4c6e-9a53-2f3b8c1f4e7a",
+		Password: "secret",
+		Confirm:  "secret",
+		Items:    []TestRulesItem{{Name: "item"}},
+		Tags:     []string{"go", "phi"},
+		Labels:   map[string]string{"x": "a"},
+	}
+}
+
+func TestValidateRules(t *testing.T) {
+	if _, err := handleValidate(&TestRulesStruct{
+		Age: 20, Code: "abcd", Slug: "phi", Email: "phi@philip.id", ID: "0b9d6f0e-5d6f-4c6e-9a53-2f3b8c1f4e7a",
+	}); err != nil {
+		t.Fatalf("expected zero values of optional fields to pass, got %s", err.Message)
+	}
+
+	if _, err := handleValidate(&[]TestRulesStruct{validRulesStruct()}); err != nil {
+		t.Fatalf("expected valid struct to pass, got %s", err.Message)
+	}
+
+	website := "philip.id"
+	tests := []struct {
+		name   string
+		modify func(s *TestRulesStruct)
+		msg    string
+	}{
+		{"min", func(s *TestRulesStruct) { s.Age = 17 }, "invalid 'age' (must be at least 18)"},
+		{"max", func(s *TestRulesStruct) { s.Age = 131 }, "invalid 'age' (must be at most 130)"},
+		{"len", func(s *TestRulesStruct) { s.Code = "äbc" }, "invalid 'code' (length must be 4)"},
+		{"oneof", func(s *TestRulesStruct) { s.Color = "pink" }, "invalid 'color' (must be one of [red green blue])"},
+		{"regexp", func(s *TestRulesStruct) { s.Slug = "phi1" }, "invalid 'slug' (must match ^[a-z]{2,4}$)"},
+		{"email", func(s *TestRulesStruct) { s.Email = "Phi <phi@philip.id>" }, "invalid 'email' (must be a valid email)"},
+		{"url", func(s *TestRulesStruct) { s.Website = &website }, "invalid 'website' (must be a valid url)"},
+		{"uuid", func(s *TestRulesStruct) { s.ID = "1337" }, "invalid 'id' (must be a valid uuid)"},
+		{"eqfield", func(s *TestRulesStruct) { s.Confirm = "secreT" }, "invalid 'confirm' (must be equal to Password)"},
+		{"nested", func(s *TestRulesStruct) {
+			s.Items = []TestRulesItem{{Name: "item"}, {Name: "it"}, {}}
+		}, "missing 'items[2].name'; invalid 'items[1].name' (length must be at least 3)"},
+		{"slice length", func(s *TestRulesStruct) { s.Items = make([]TestRulesItem, 4) }, "missing 'items[0].name, items[1].name, items[2].name, items[3].name'; invalid 'items' (length must be at most 3)"},
+		{"dive slice", func(s *TestRulesStruct) { s.Tags = []string{"go", "p"} }, "invalid 'tags[1]' (length must be at least 2)"},
+		{"dive map", func(s *TestRulesStruct) { s.Labels = map[string]string{"y": "c", "x": "b"} }, "invalid 'labels[y]' (must be one of [a b])"},
+	}
+
+	for _, tt := range tests {
+		s := validRulesStruct()
+		tt.modify(&s)
+
+		_, err := handleValidate(&s)
+		if err == nil {
+			t.Errorf("%s: expected validation error", tt.name)
+			continue
+		}
+		if err.Message != tt.msg {
+			t.Errorf("%s: validation went wrong have (%s) want (%s)", tt.name, err.Message, tt.msg)
+		}
+	}
+}
+
+func TestValidateSecret(t *testing.T) {
+	_, err := handleValidate(&struct {
+		User     string   `json:"user" validate:"min=3"`
+		Password string   `json:"password" validate:"secret,min=12"`
+		Tokens   []string `json:"tokens" validate:"secret,dive,len=8"`
+	}{"ph", "hunter2", []string{"abc"}})
+	if err == nil || len(err.Details) != 3 {
+		t.Fatalf("expected validation error, got %v", err)
+	}
+
+	values := []interface{}{err.Details[0].Value, err.Details[1].Value, err.Details[2].Value}
+	if values[0] != "ph" || values[1] != nil || values[2] != nil {
+		t.Errorf("expected only the value of user, got %v", values)
+	}
+}
+
+func TestValidateInvalidTag(t *testing.T) {
+	_, err := handleValidate(&struct {
+		Name string `json:"name" validate:"between=1"`
+	}{})
+	if err == nil || err.Error != "validationFailed" || err.StatusCode != 500 {
+		t.Errorf("expected validationFailed error, got %v", err)
+	}
+}
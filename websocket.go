@@ -0,0 +1,723 @@
+package phi
+
+import (
+	"bufio"
+	"bytes"
+	"compress/flate"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// WebSocket message types, see RFC 6455 section 11.8
+const (
+	TextMessage   = 1
+	BinaryMessage = 2
+	CloseMessage  = 8
+	PingMessage   = 9
+	PongMessage   = 10
+)
+
+// WebSocket close codes, see RFC 6455 section 7.4.1
+const (
+	CloseNormalClosure           = 1000
+	CloseGoingAway               = 1001
+	CloseProtocolError           = 1002
+	CloseUnsupportedData         = 1003
+	CloseNoStatusReceived        = 1005
+	CloseAbnormalClosure         = 1006
+	CloseInvalidFramePayloadData = 1007
+	ClosePolicyViolation         = 1008
+	CloseMessageTooBig           = 1009
+	CloseMandatoryExtension      = 1010
+	CloseInternalServerErr       = 1011
+)
+
+const (
+	websocketGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+	// default limit of the size of received messages
+	defaultReadLimit = 32 << 20
+
+	// time to wait for the close frame of the peer when closing
+	closeTimeout = time.Second
+
+	// size of the sliding window of deflate streams
+	deflateWindow = 32 << 10
+)
+
+var (
+	// ErrWebSocketClosed is returned when writing to a connection after a
+	// close frame has been sent
+	ErrWebSocketClosed = errors.New("phi: websocket closed")
+
+	// appended to compressed messages before inflating them, the sync flush
+	// marker stripped by the sender and an empty final block
+	deflateTail = []byte{0x00, 0x00, 0xff, 0xff, 0x01, 0x00, 0x00, 0xff, 0xff}
+
+	flateWriters = sync.Pool{New: func() interface{} {
+		w, _ := flate.NewWriter(nil, flate.BestSpeed)
+		return w
+	}}
+)
+
+// CloseError is returned by Conn.ReadMessage once the connection is closed,
+// by the peer or due to a protocol violation.
+type CloseError struct {
+	Code int
+	Text string
+}
+
+func (e *CloseError) Error() string {
+	return fmt.Sprintf("websocket: close %d %s", e.Code, e.Text)
+}
+
+// WebSocketOptions configure the upgrade of requests to websockets, see
+// WebSocketHandler
+type WebSocketOptions struct {
+	// Subprotocols supported by the server in order of preference
+	Subprotocols []string
+
+	// CheckOrigin reports whether the request is allowed, by default the
+	// Origin header has to match the Host of the request if present
+	CheckOrigin func(r *http.Request) bool
+
+	// ReadLimit is the maximum size of received messages, 32MB if zero
+	ReadLimit int64
+
+	// DisableCompression disables the negotiation of permessage-deflate
+	DisableCompression bool
+}
+
+// Conn is a websocket connection (RFC 6455). The request it was upgraded
+// from is embedded, so URL parameters and context values set by middlewares
+// are available:
+//
+//	r.WebSocket("/rooms/{id}", func(c *phi.Conn) *phi.Error {
+//		id, _ := c.URLParam("id")
+//		token := middleware.GetToken(c.Request)
+//		...
+//	})
+//
+// ReadMessage must not be called concurrently, writes are safe for
+// concurrent use.
+type Conn struct {
+	*Request
+
+	conn        net.Conn
+	br          *bufio.Reader
+	subprotocol string
+	readLimit   int64
+
+	// permessage-deflate, window holds the recent output of the client
+	// compressor if it keeps its context between messages
+	compress bool
+	takeover bool
+	window   []byte
+
+	readErr       error
+	closeReceived bool
+	pongHandler   func(data []byte)
+
+	writeMu   sync.Mutex
+	closeSent bool
+}
+
+// frame is a single websocket frame received from the client
+type frame struct {
+	fin     bool
+	rsv1    bool
+	opcode  int
+	payload []byte
+}
+
+// WebSocketHandler returns a handler upgrading requests to websocket
+// connections before calling fn. Errors of the handshake are written with
+// HandleError, the connection is closed once fn returns, with the close
+// code 1011 and the message of the error if it returns one.
+//
+//	r.Method("GET", "/ws", phi.WebSocketHandler(echo, phi.WebSocketOptions{
+//		Subprotocols: []string{"chat"},
+//	}))
+func WebSocketHandler(fn func(c *Conn) *Error, opts WebSocketOptions) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		c, err := opts.upgrade(w, r)
+		if err != nil {
+			HandleError(w, r, err)
+			return
+		}
+		if c == nil {
+			return
+		}
+
+		code, reason := CloseNormalClosure, ""
+		if err := fn(c); err != nil {
+			code, reason = CloseInternalServerErr, err.Message
+		}
+
+		c.Close(code, reason)
+	})
+}
+
+// upgrade performs the opening handshake and hijacks the connection
+func (o WebSocketOptions) upgrade(w http.ResponseWriter, r *http.Request) (*Conn, *Error) {
+	if r.Method != http.MethodGet ||
+		!headerContainsToken(r.Header, "Connection", "upgrade") ||
+		!headerContainsToken(r.Header, "Upgrade", "websocket") {
+		return nil, websocketError("not a websocket handshake", http.StatusBadRequest)
+	}
+
+	if r.Header.Get("Sec-WebSocket-Version") != "13" {
+		w.Header().Set("Sec-WebSocket-Version", "13")
+		return nil, websocketError("unsupported websocket version", http.StatusUpgradeRequired)
+	}
+
+	key := r.Header.Get("Sec-WebSocket-Key")
+	if decoded, err := base64.StdEncoding.DecodeString(key); err != nil || len(decoded) != 16 {
+		return nil, websocketError("invalid Sec-WebSocket-Key", http.StatusBadRequest)
+	}
+
+	checkOrigin := o.CheckOrigin
+	if checkOrigin == nil {
+		checkOrigin = sameOrigin
+	}
+	if !checkOrigin(r) {
+		return nil, websocketError("origin not allowed", http.StatusForbidden)
+	}
+
+	hj, ok := hijacker(w)
+	if !ok {
+		return nil, websocketError("response writer does not support hijacking", http.StatusInternalServerError)
+	}
+
+	c := &Conn{
+		Request:   &Request{Request: r},
+		readLimit: o.ReadLimit,
+	}
+	if c.readLimit <= 0 {
+		c.readLimit = defaultReadLimit
+	}
+
+	// response headers set by middlewares so far are kept
+	header := http.Header{}
+	for k, v := range w.Header() {
+		header[k] = v
+	}
+	header.Set("Upgrade", "websocket")
+	header.Set("Connection", "Upgrade")
+	header.Set("Sec-WebSocket-Accept", acceptKey(key))
+
+	if c.subprotocol = selectSubprotocol(r, o.Subprotocols); c.subprotocol != "" {
+		header.Set("Sec-WebSocket-Protocol", c.subprotocol)
+	}
+
+	if !o.DisableCompression {
+		if ext, takeover, ok := negotiateDeflate(r.Header); ok {
+			c.compress, c.takeover = true, takeover
+			header.Set("Sec-WebSocket-Extensions", ext)
+		}
+	}
+
+	conn, brw, err := hj.Hijack()
+	if err != nil {
+		return nil, websocketError(err.Error(), http.StatusInternalServerError)
+	}
+
+	// clear deadlines set by the http server
+	conn.SetDeadline(time.Time{})
+
+	c.conn, c.br = conn, brw.Reader
+
+	brw.Writer.WriteString("HTTP/1.1 101 Switching Protocols\r\n")
+	header.Write(brw.Writer)
+	brw.Writer.WriteString("\r\n")
+	if err := brw.Writer.Flush(); err != nil {
+		// the connection is hijacked already, there is nobody to respond to
+		conn.Close()
+		return nil, nil
+	}
+
+	return c, nil
+}
+
+// Subprotocol returns the negotiated subprotocol, empty if none
+func (c *Conn) Subprotocol() string {
+	return c.subprotocol
+}
+
+// SetReadDeadline sets the deadline for reading messages
+func (c *Conn) SetReadDeadline(t time.Time) error {
+	return c.conn.SetReadDeadline(t)
+}
+
+// SetWriteDeadline sets the deadline for writing messages
+func (c *Conn) SetWriteDeadline(t time.Time) error {
+	return c.conn.SetWriteDeadline(t)
+}
+
+// SetPongHandler sets the function called with the data of received pongs.
+// Pings of the client are answered automatically.
+func (c *Conn) SetPongHandler(fn func(data []byte)) {
+	c.pongHandler = fn
+}
+
+// ReadMessage reads the next text or binary message, control frames
+// received meanwhile are handled. A *CloseError is returned once the
+// connection is closed.
+func (c *Conn) ReadMessage() (messageType int, data []byte, err error) {
+	if c.readErr != nil {
+		return 0, nil, c.readErr
+	}
+
+	messageType, data, err = c.readMessage()
+	if err != nil {
+		c.readErr = err
+	}
+
+	return messageType, data, err
+}
+
+// ReadJSON reads the next message and decodes it as json into v
+func (c *Conn) ReadJSON(v interface{}) error {
+	_, data, err := c.ReadMessage()
+	if err != nil {
+		return err
+	}
+
+	return json.Unmarshal(data, v)
+}
+
+// WriteMessage writes a text or binary message
+func (c *Conn) WriteMessage(messageType int, data []byte) error {
+	if messageType != TextMessage && messageType != BinaryMessage {
+		return fmt.Errorf("phi: invalid websocket message type %d", messageType)
+	}
+
+	if !c.compress {
+		return c.writeFrame(messageType, data, false)
+	}
+
+	var buf bytes.Buffer
+	fw := flateWriters.Get().(*flate.Writer)
+	defer flateWriters.Put(fw)
+
+	fw.Reset(&buf)
+	if _, err := fw.Write(data); err != nil {
+		return err
+	}
+	if err := fw.Flush(); err != nil {
+		return err
+	}
+
+	// strip the sync flush marker, see RFC 7692 section 7.2.1
+	return c.writeFrame(messageType, bytes.TrimSuffix(buf.Bytes(), deflateTail[:4]), true)
+}
+
+// WriteJSON writes v as json text message
+func (c *Conn) WriteJSON(v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+
+	return c.WriteMessage(TextMessage, data)
+}
+
+// Ping sends a ping, the client answers with a pong carrying the same data
+func (c *Conn) Ping(data []byte) error {
+	if len(data) > 125 {
+		return errors.New("phi: ping data too large")
+	}
+
+	return c.writeFrame(PingMessage, data, false)
+}
+
+// Close performs the closing handshake with the code and reason and closes
+// the underlying connection. It is called automatically once the handler
+// returns.
+func (c *Conn) Close(code int, reason string) error {
+	if len(reason) > 123 {
+		reason = reason[:123]
+	}
+
+	payload := make([]byte, 2, 2+len(reason))
+	binary.BigEndian.PutUint16(payload, uint16(code))
+	payload = append(payload, reason...)
+
+	if err := c.writeFrame(CloseMessage, payload, false); err == nil && !c.closeReceived && c.readErr == nil {
+		// wait for the close frame of the client
+		c.conn.SetReadDeadline(time.Now().Add(closeTimeout))
+		for {
+			if _, _, err := c.readMessage(); err != nil {
+				break
+			}
+		}
+	}
+
+	return c.conn.Close()
+}
+
+func (c *Conn) readMessage() (int, []byte, error) {
+	var (
+		messageType int
+		compressed  bool
+		payload     []byte
+	)
+
+	for {
+		f, err := c.readFrame()
+		if err != nil {
+			return 0, nil, err
+		}
+
+		switch f.opcode {
+		case PingMessage:
+			if err := c.writeFrame(PongMessage, f.payload, false); err != nil && err != ErrWebSocketClosed {
+				return 0, nil, err
+			}
+			continue
+
+		case PongMessage:
+			if c.pongHandler != nil {
+				c.pongHandler(f.payload)
+			}
+			continue
+
+		case CloseMessage:
+			return 0, nil, c.handleClose(f.payload)
+
+		case 0:
+			if messageType == 0 {
+				return 0, nil, c.fail(CloseProtocolError, "unexpected continuation frame")
+			}
+
+		default:
+			if messageType != 0 {
+				return 0, nil, c.fail(CloseProtocolError, "expected continuation frame")
+			}
+			messageType, compressed = f.opcode, f.rsv1
+		}
+
+		if int64(len(payload)+len(f.payload)) > c.readLimit {
+			return 0, nil, c.fail(CloseMessageTooBig, "message too big")
+		}
+		payload = append(payload, f.payload...)
+
+		if !f.fin {
+			continue
+		}
+
+		if compressed {
+			if payload, err = c.inflate(payload); err != nil {
+				return 0, nil, err
+			}
+		}
+
+		if messageType == TextMessage && !utf8.Valid(payload) {
+			return 0, nil, c.fail(CloseInvalidFramePayloadData, "invalid utf-8")
+		}
+
+		return messageType, payload, nil
+	}
+}
+
+// readFrame reads and unmasks a single frame
+func (c *Conn) readFrame() (*frame, error) {
+	var h [8]byte
+	if _, err := io.ReadFull(c.br, h[:2]); err != nil {
+		return nil, c.readError(err)
+	}
+
+	f := &frame{
+		fin:    h[0]&0x80 != 0,
+		rsv1:   h[0]&0x40 != 0,
+		opcode: int(h[0] & 0x0f),
+	}
+	masked := h[1]&0x80 != 0
+	n := uint64(h[1] & 0x7f)
+
+	switch {
+	case h[0]&0x30 != 0, f.rsv1 && (!c.compress || f.opcode == 0 || f.opcode >= CloseMessage):
+		return nil, c.fail(CloseProtocolError, "invalid reserved bits")
+	case f.opcode > BinaryMessage && f.opcode < CloseMessage, f.opcode > PongMessage:
+		return nil, c.fail(CloseProtocolError, fmt.Sprintf("unknown opcode %d", f.opcode))
+	case !masked:
+		return nil, c.fail(CloseProtocolError, "unmasked client frame")
+	}
+
+	switch n {
+	case 126:
+		if _, err := io.ReadFull(c.br, h[:2]); err != nil {
+			return nil, c.readError(err)
+		}
+		n = uint64(binary.BigEndian.Uint16(h[:2]))
+	case 127:
+		if _, err := io.ReadFull(c.br, h[:8]); err != nil {
+			return nil, c.readError(err)
+		}
+		n = binary.BigEndian.Uint64(h[:8])
+	}
+
+	if f.opcode >= CloseMessage && (!f.fin || n > 125) {
+		return nil, c.fail(CloseProtocolError, "invalid control frame")
+	}
+	if n > uint64(c.readLimit) {
+		return nil, c.fail(CloseMessageTooBig, "message too big")
+	}
+
+	var mask [4]byte
+	if _, err := io.ReadFull(c.br, mask[:]); err != nil {
+		return nil, c.readError(err)
+	}
+
+	f.payload = make([]byte, n)
+	if _, err := io.ReadFull(c.br, f.payload); err != nil {
+		return nil, c.readError(err)
+	}
+	for i := range f.payload {
+		f.payload[i] ^= mask[i%4]
+	}
+
+	return f, nil
+}
+
+// handleClose answers the close frame of the client
+func (c *Conn) handleClose(payload []byte) error {
+	c.closeReceived = true
+
+	if len(payload) == 0 {
+		c.writeFrame(CloseMessage, nil, false)
+		return &CloseError{Code: CloseNoStatusReceived}
+	}
+
+	if len(payload) < 2 {
+		return c.fail(CloseProtocolError, "invalid close frame")
+	}
+
+	code := int(binary.BigEndian.Uint16(payload))
+	if !validCloseCode(code) {
+		return c.fail(CloseProtocolError, fmt.Sprintf("invalid close code %d", code))
+	}
+	if !utf8.Valid(payload[2:]) {
+		return c.fail(CloseInvalidFramePayloadData, "invalid utf-8 close reason")
+	}
+
+	c.writeFrame(CloseMessage, payload[:2], false)
+	return &CloseError{Code: code, Text: string(payload[2:])}
+}
+
+// fail sends a close frame due to a protocol violation of the client
+func (c *Conn) fail(code int, reason string) error {
+	payload := make([]byte, 2, 2+len(reason))
+	binary.BigEndian.PutUint16(payload, uint16(code))
+	c.writeFrame(CloseMessage, append(payload, reason...), false)
+
+	return &CloseError{Code: code, Text: reason}
+}
+
+// readError reports connections closed without closing handshake as
+// abnormal closure
+func (c *Conn) readError(err error) error {
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		return &CloseError{Code: CloseAbnormalClosure, Text: err.Error()}
+	}
+
+	return err
+}
+
+// inflate decompresses a message of the permessage-deflate extension
+func (c *Conn) inflate(payload []byte) ([]byte, error) {
+	r := flate.NewReader(io.MultiReader(bytes.NewReader(payload), bytes.NewReader(deflateTail)))
+	if c.takeover {
+		r = flate.NewReaderDict(io.MultiReader(bytes.NewReader(payload), bytes.NewReader(deflateTail)), c.window)
+	}
+	defer r.Close()
+
+	data, err := io.ReadAll(io.LimitReader(r, c.readLimit+1))
+	if err != nil {
+		return nil, c.fail(CloseInvalidFramePayloadData, "invalid compressed message")
+	}
+	if int64(len(data)) > c.readLimit {
+		return nil, c.fail(CloseMessageTooBig, "message too big")
+	}
+
+	if c.takeover {
+		c.window = append(c.window, data...)
+		if len(c.window) > deflateWindow {
+			c.window = append([]byte(nil), c.window[len(c.window)-deflateWindow:]...)
+		}
+	}
+
+	return data, nil
+}
+
+// writeFrame writes a single unmasked frame
+func (c *Conn) writeFrame(opcode int, payload []byte, rsv1 bool) error {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+
+	if c.closeSent {
+		return ErrWebSocketClosed
+	}
+	if opcode == CloseMessage {
+		c.closeSent = true
+	}
+
+	buf := make([]byte, 0, 10+len(payload))
+
+	b0 := byte(0x80 | opcode)
+	if rsv1 {
+		b0 |= 0x40
+	}
+	buf = append(buf, b0)
+
+	switch n := len(payload); {
+	case n <= 125:
+		buf = append(buf, byte(n))
+	case n <= 0xffff:
+		buf = append(buf, 126, byte(n>>8), byte(n))
+	default:
+		var size [8]byte
+		binary.BigEndian.PutUint64(size[:], uint64(n))
+		buf = append(append(buf, 127), size[:]...)
+	}
+
+	_, err := c.conn.Write(append(buf, payload...))
+	return err
+}
+
+// validCloseCode reports whether the code may be sent in a close frame
+func validCloseCode(code int) bool {
+	switch {
+	case code >= 1000 && code <= 1003, code >= 1007 && code <= 1014:
+		return true
+	}
+
+	return code >= 3000 && code <= 4999
+}
+
+func websocketError(msg string, status int) *Error {
+	return &Error{
+		Error:      "websocketHandshake",
+		Message:    msg,
+		StatusCode: status,
+	}
+}
+
+func acceptKey(key string) string {
+	h := sha1.New()
+	h.Write([]byte(key + websocketGUID))
+
+	return base64.StdEncoding.EncodeToString(h.Sum(nil))
+}
+
+// hijacker finds the http.Hijacker of the writer, unwrapping middleware
+// writers if needed
+func hijacker(w http.ResponseWriter) (http.Hijacker, bool) {
+	for {
+		if hj, ok := w.(http.Hijacker); ok {
+			return hj, true
+		}
+
+		u, ok := w.(interface{ Unwrap() http.ResponseWriter })
+		if !ok {
+			return nil, false
+		}
+		w = u.Unwrap()
+	}
+}
+
+// sameOrigin allows requests without Origin header and those whose origin
+// matches the host
+func sameOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+
+	_, host, ok := strings.Cut(origin, "://")
+	return ok && strings.EqualFold(host, r.Host)
+}
+
+// selectSubprotocol returns the first supported protocol offered by the client
+func selectSubprotocol(r *http.Request, supported []string) string {
+	for _, p := range supported {
+		if headerContainsToken(r.Header, "Sec-WebSocket-Protocol", p) {
+			return p
+		}
+	}
+
+	return ""
+}
+
+// negotiateDeflate accepts the first permessage-deflate offer the server can
+// handle. The server never keeps its compression context, the client may.
+func negotiateDeflate(h http.Header) (ext string, takeover bool, ok bool) {
+	for _, offer := range headerValues(h, "Sec-WebSocket-Extensions") {
+		params := strings.Split(offer, ";")
+		if strings.TrimSpace(params[0]) != "permessage-deflate" {
+			continue
+		}
+
+		takeover, ok = true, true
+		for _, p := range params[1:] {
+			name, value, _ := strings.Cut(strings.TrimSpace(p), "=")
+			switch name {
+			case "server_no_context_takeover", "client_max_window_bits":
+			case "client_no_context_takeover":
+				takeover = false
+			case "server_max_window_bits":
+				// compress/flate always uses a 32KB window
+				ok = strings.Trim(value, `"`) == "15"
+			default:
+				ok = false
+			}
+		}
+
+		if ok {
+			ext = "permessage-deflate; server_no_context_takeover"
+			if !takeover {
+				ext += "; client_no_context_takeover"
+			}
+			return ext, takeover, true
+		}
+	}
+
+	return "", false, false
+}
+
+// headerValues splits the comma separated values of the header
+func headerValues(h http.Header, key string) []string {
+	values := []string{}
+	for _, v := range h.Values(key) {
+		for _, s := range strings.Split(v, ",") {
+			if s = strings.TrimSpace(s); s != "" {
+				values = append(values, s)
+			}
+		}
+	}
+
+	return values
+}
+
+// headerContainsToken reports whether the comma separated header contains
+// the token, case insensitive
+func headerContainsToken(h http.Header, key, token string) bool {
+	for _, v := range headerValues(h, key) {
+		if strings.EqualFold(v, token) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,326 @@
+package phi
+
+import (
+	"bufio"
+	"bytes"
+	"compress/flate"
+	"context"
+	"encoding/binary"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+type wsClient struct {
+	t    *testing.T
+	conn net.Conn
+	br   *bufio.Reader
+}
+
+// dialWebSocket performs the opening handshake against the test server, the
+// client is nil if the server does not switch protocols
+func dialWebSocket(t *testing.T, ts *httptest.Server, path string, header http.Header) (*wsClient, *http.Response) {
+	conn, err := net.Dial("tcp", ts.Listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req, _ := http.NewRequest("GET", ts.URL+path, nil)
+	req.Header.Set("Connection", "Upgrade")
+	req.Header.Set("Upgrade", "websocket")
+	req.Header.Set("Sec-WebSocket-Version", "13")
+	req.Header.Set("Sec-WebSocket-Key", "dGhlIHNhbXBsZSBub25jZQ==")
+	for k, v := range header {
+		req.Header[k] = v
+	}
+
+	if err := req.Write(conn); err != nil {
+		t.Fatal(err)
+	}
+
+	br := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(br, req)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if resp.StatusCode != http.StatusSwitchingProtocols {
+		conn.Close()
+		return nil, resp
+	}
+
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+	return &wsClient{t: t, conn: conn, br: br}, resp
+}
+
+func (c *wsClient) write(opcode int, fin, rsv1, masked bool, payload []byte) {
+	b0 := byte(opcode)
+	if fin {
+		b0 |= 0x80
+	}
+	if rsv1 {
+		b0 |= 0x40
+	}
+
+	b1 := byte(0)
+	if masked {
+		b1 = 0x80
+	}
+
+	buf := []byte{b0}
+	switch n := len(payload); {
+	case n <= 125:
+		buf = append(buf, b1|byte(n))
+	case n <= 0xffff:
+		buf = append(buf, b1|126, byte(n>>8), byte(n))
+	default:
+		var size [8]byte
+		binary.BigEndian.PutUint64(size[:], uint64(n))
+		buf = append(append(buf, b1|127), size[:]...)
+	}
+
+	data := append([]byte{}, payload...)
+	if masked {
+		mask := []byte{1, 2, 3, 4}
+		buf = append(buf, mask...)
+		for i := range data {
+			data[i] ^= mask[i%4]
+		}
+	}
+
+	if _, err := c.conn.Write(append(buf, data...)); err != nil {
+		c.t.Fatal(err)
+	}
+}
+
+func (c *wsClient) send(opcode int, payload []byte) {
+	c.write(opcode, true, false, true, payload)
+}
+
+func (c *wsClient) read() (opcode int, rsv1 bool, payload []byte) {
+	var h [2]byte
+	if _, err := io.ReadFull(c.br, h[:]); err != nil {
+		c.t.Fatal(err)
+	}
+
+	n := uint64(h[1] & 0x7f)
+	switch n {
+	case 126:
+		var size [2]byte
+		io.ReadFull(c.br, size[:])
+		n = uint64(binary.BigEndian.Uint16(size[:]))
+	case 127:
+		var size [8]byte
+		io.ReadFull(c.br, size[:])
+		n = binary.BigEndian.Uint64(size[:])
+	}
+
+	if h[1]&0x80 != 0 {
+		c.t.Fatal("server frames must not be masked")
+	}
+
+	payload = make([]byte, n)
+	if _, err := io.ReadFull(c.br, payload); err != nil {
+		c.t.Fatal(err)
+	}
+
+	return int(h[0] & 0x0f), h[0]&0x40 != 0, payload
+}
+
+func (c *wsClient) expectClose(code int, reason string) {
+	opcode, _, payload := c.read()
+	if opcode != CloseMessage || len(payload) < 2 || int(binary.BigEndian.Uint16(payload)) != code || string(payload[2:]) != reason {
+		c.t.Fatalf("expected close %d %q, got opcode %d %q", code, reason, opcode, payload)
+	}
+}
+
+func closePayload(code int, reason string) []byte {
+	payload := make([]byte, 2)
+	binary.BigEndian.PutUint16(payload, uint16(code))
+	return append(payload, reason...)
+}
+
+type wsCtxKey struct{}
+
+func testWebSocketRouter() *Mux {
+	r := NewRouter()
+	r.Use(func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), wsCtxKey{}, "user")))
+		})
+	})
+
+	r.WebSocket("/echo/{room}", func(c *Conn) *Error {
+		room, _ := c.URLParam("room")
+		for {
+			t, msg, err := c.ReadMessage()
+			if err != nil {
+				return nil
+			}
+
+			prefix := room + "/" + c.Context().Value(wsCtxKey{}).(string) + ": "
+			if err := c.WriteMessage(t, append([]byte(prefix), msg...)); err != nil {
+				return UnknownError(err)
+			}
+		}
+	})
+
+	r.WebSocket("/fail", func(c *Conn) *Error {
+		return &Error{Error: "failed", Message: "something failed"}
+	})
+
+	return r
+}
+
+func TestWebSocketEcho(t *testing.T) {
+	ts := httptest.NewServer(testWebSocketRouter())
+	defer ts.Close()
+
+	c, resp := dialWebSocket(t, ts, "/echo/lobby", nil)
+	if c == nil {
+		t.Fatalf("unexpected handshake response %d", resp.StatusCode)
+	}
+	if accept := resp.Header.Get("Sec-WebSocket-Accept"); accept != "s3pPLMBiTxaQ9kYGzzhZRbK+xOo=" {
+		t.Fatalf("unexpected accept key %s", accept)
+	}
+	if ext := resp.Header.Get("Sec-WebSocket-Extensions"); ext != "" {
+		t.Fatalf("unexpected extensions %s", ext)
+	}
+
+	c.send(TextMessage, []byte("hello"))
+	if opcode, _, payload := c.read(); opcode != TextMessage || string(payload) != "lobby/user: hello" {
+		t.Fatalf("unexpected message %d %q", opcode, payload)
+	}
+
+	// fragmented message with interleaved ping
+	c.write(BinaryMessage, false, false, true, []byte("frag"))
+	c.send(PingMessage, []byte("ping"))
+	c.write(0, true, false, true, bytes.Repeat([]byte("x"), 300))
+
+	if opcode, _, payload := c.read(); opcode != PongMessage || string(payload) != "ping" {
+		t.Fatalf("expected pong, got %d %q", opcode, payload)
+	}
+	if opcode, _, payload := c.read(); opcode != BinaryMessage || string(payload) != "lobby/user: frag"+string(bytes.Repeat([]byte("x"), 300)) {
+		t.Fatalf("unexpected message %d %q", opcode, payload)
+	}
+
+	c.send(CloseMessage, closePayload(CloseGoingAway, "bye"))
+	c.expectClose(CloseGoingAway, "")
+}
+
+func TestWebSocketClose(t *testing.T) {
+	ts := httptest.NewServer(testWebSocketRouter())
+	defer ts.Close()
+
+	// errors returned by the handler close with 1011
+	c, _ := dialWebSocket(t, ts, "/fail", nil)
+	c.expectClose(CloseInternalServerErr, "something failed")
+	c.send(CloseMessage, closePayload(CloseInternalServerErr, ""))
+
+	tests := []struct {
+		name string
+		send func(c *wsClient)
+		code int
+	}{
+		{"unmasked", func(c *wsClient) { c.write(TextMessage, true, false, false, []byte("hi")) }, CloseProtocolError},
+		{"reserved bits", func(c *wsClient) { c.write(TextMessage, true, true, true, []byte("hi")) }, CloseProtocolError},
+		{"unknown opcode", func(c *wsClient) { c.send(3, nil) }, CloseProtocolError},
+		{"continuation", func(c *wsClient) { c.send(0, []byte("hi")) }, CloseProtocolError},
+		{"fragmented ping", func(c *wsClient) { c.write(PingMessage, false, false, true, nil) }, CloseProtocolError},
+		{"invalid utf8", func(c *wsClient) { c.send(TextMessage, []byte{0xff, 0xfe}) }, CloseInvalidFramePayloadData},
+		{"invalid close code", func(c *wsClient) { c.send(CloseMessage, closePayload(1005, "")) }, CloseProtocolError},
+	}
+
+	for _, tt := range tests {
+		c, _ := dialWebSocket(t, ts, "/echo/lobby", nil)
+		tt.send(c)
+
+		opcode, _, payload := c.read()
+		if opcode != CloseMessage || int(binary.BigEndian.Uint16(payload)) != tt.code {
+			t.Errorf("%s: expected close %d, got %d %q", tt.name, tt.code, opcode, payload)
+		}
+		c.conn.Close()
+	}
+}
+
+func TestWebSocketCompression(t *testing.T) {
+	ts := httptest.NewServer(testWebSocketRouter())
+	defer ts.Close()
+
+	c, resp := dialWebSocket(t, ts, "/echo/lobby", http.Header{
+		"Sec-Websocket-Extensions": {"permessage-deflate; server_max_window_bits=10, permessage-deflate; client_max_window_bits"},
+	})
+	if c == nil {
+		t.Fatalf("unexpected handshake response %d", resp.StatusCode)
+	}
+	if ext := resp.Header.Get("Sec-WebSocket-Extensions"); ext != "permessage-deflate; server_no_context_takeover" {
+		t.Fatalf("unexpected extensions %s", ext)
+	}
+
+	// the client keeps its compression context between messages
+	var buf bytes.Buffer
+	fw, _ := flate.NewWriter(&buf, flate.BestCompression)
+
+	for _, msg := range []string{"hello hello hello", "hello hello hello"} {
+		buf.Reset()
+		fw.Write([]byte(msg))
+		fw.Flush()
+		c.write(TextMessage, true, true, true, bytes.TrimSuffix(buf.Bytes(), []byte{0, 0, 0xff, 0xff}))
+
+		opcode, rsv1, payload := c.read()
+		if opcode != TextMessage || !rsv1 {
+			t.Fatalf("expected compressed text message, got %d %v", opcode, rsv1)
+		}
+
+		data, err := io.ReadAll(flate.NewReader(io.MultiReader(bytes.NewReader(payload), bytes.NewReader(deflateTail))))
+		if err != nil || string(data) != "lobby/user: "+msg {
+			t.Fatalf("unexpected message %q %v", data, err)
+		}
+	}
+}
+
+func TestWebSocketHandshake(t *testing.T) {
+	ts := httptest.NewServer(testWebSocketRouter())
+	defer ts.Close()
+
+	if _, resp := dialWebSocket(t, ts, "/echo/lobby", http.Header{"Upgrade": {"h2c"}}); resp.StatusCode != 400 {
+		t.Errorf("expected 400, got %d", resp.StatusCode)
+	}
+
+	if _, resp := dialWebSocket(t, ts, "/echo/lobby", http.Header{"Sec-Websocket-Version": {"8"}}); resp.StatusCode != 426 || resp.Header.Get("Sec-WebSocket-Version") != "13" {
+		t.Errorf("expected 426, got %d", resp.StatusCode)
+	}
+
+	if _, resp := dialWebSocket(t, ts, "/echo/lobby", http.Header{"Origin": {"https://evil.example"}}); resp.StatusCode != 403 {
+		t.Errorf("expected 403, got %d", resp.StatusCode)
+	}
+
+	r := NewRouter()
+	r.Method("GET", "/ws", WebSocketHandler(func(c *Conn) *Error {
+		if err := c.WriteMessage(TextMessage, []byte(c.Subprotocol())); err != nil {
+			return UnknownError(err)
+		}
+
+		return nil
+	}, WebSocketOptions{
+		Subprotocols: []string{"v2", "v1"},
+		CheckOrigin:  func(r *http.Request) bool { return true },
+	}))
+
+	ts2 := httptest.NewServer(r)
+	defer ts2.Close()
+
+	c, resp := dialWebSocket(t, ts2, "/ws", http.Header{
+		"Origin":                 {"https://other.example"},
+		"Sec-Websocket-Protocol": {"v1, v2"},
+	})
+	if c == nil || resp.Header.Get("Sec-WebSocket-Protocol") != "v2" {
+		t.Fatalf("unexpected handshake response %d %v", resp.StatusCode, resp.Header)
+	}
+	if _, _, payload := c.read(); string(payload) != "v2" {
+		t.Errorf("unexpected subprotocol %q", payload)
+	}
+}